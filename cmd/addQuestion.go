@@ -5,43 +5,81 @@ import (
 	"flag"
 	"fmt"
 	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
 	"github.com/google/uuid"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 const (
-	FilePath = "./questions.json"
+	PacksDir = "./packs"
 )
 
 func main() {
 	qEn := flag.String("qEn", "", "the question to add in english")
 	qNo := flag.String("qNo", "", "the question to add in norwegian")
+	pack := flag.String("pack", "classic", "the question pack to add the question to")
+	db := flag.String("db", "", "the SQLite questions database to add the question to, the pack files are used when it is empty")
 	flag.Parse()
 	if *qEn == "" && *qNo == "" {
 		fmt.Println("at least one question must be added")
 		os.Exit(1)
 	}
 
-	addQuestion(*qEn, *qNo)
+	if *db != "" {
+		addQuestionToDB(*db, *pack, *qEn, *qNo)
+		return
+	}
+	addQuestion(filepath.Join(PacksDir, *pack, question.QuestionsFileName), *qEn, *qNo)
 }
 
-func addQuestion(qEn, qNo string) {
-	questions := loadQuestions()
+func addQuestion(filePath, qEn, qNo string) {
+	questions := loadQuestions(filePath)
 
 	newQuestion := models.Question{
 		Id:       newUUID(questions),
-		Question: models.QuestionTranslations{Norwegian: qNo, English: qEn},
+		Question: translations(qEn, qNo),
+		Type:     models.MostLikelyQuestion,
 	}
 
 	questions.Questions = append(questions.Questions, newQuestion)
 
-	writeQuestions(questions)
+	writeQuestions(filePath, questions)
+}
+
+// addQuestionToDB adds the question to the pack in the database, the question is served without restarting the server
+func addQuestionToDB(path, pack, qEn, qNo string) {
+	db, err := question.OpenSQLite(path)
+	if err != nil {
+		log.Fatalln("unable to open the questions database: ", err)
+	}
+	defer db.Close()
+
+	newQuestion := models.Question{
+		Id:       uuid.NewString(),
+		Question: translations(qEn, qNo),
+		Type:     models.MostLikelyQuestion,
+	}
+	if err := question.AddQuestion(db, pack, newQuestion); err != nil {
+		log.Fatalln("unable to add question: ", err)
+	}
+}
+
+func translations(qEn, qNo string) models.QuestionTranslations {
+	translations := models.QuestionTranslations{}
+	if qNo != "" {
+		translations["no"] = qNo
+	}
+	if qEn != "" {
+		translations["en"] = qEn
+	}
+	return translations
 }
 
-func loadQuestions() models.Questions {
-	f, err := os.Open(FilePath)
+func loadQuestions(filePath string) models.Questions {
+	f, err := os.Open(filePath)
 	if err != nil {
 		pwd, err := os.Getwd()
 		if err != nil {
@@ -63,12 +101,12 @@ func loadQuestions() models.Questions {
 	return questions
 }
 
-func writeQuestions(q models.Questions) {
+func writeQuestions(filePath string, q models.Questions) {
 	f, err := json.Marshal(q)
 	if err != nil {
 		log.Fatalln("unable to marshal questions: ", err)
 	}
-	err = os.WriteFile(FilePath, f, 0666)
+	err = os.WriteFile(filePath, f, 0666)
 	if err != nil {
 		log.Fatalln("unable to write questions to file: ", err)
 	}
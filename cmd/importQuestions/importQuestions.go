@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"github.com/akselleirv/introspect/question"
+	"log"
+)
+
+// importQuestions copies the question packs into a SQLite database, packs and questions which exist are updated
+func main() {
+	db := flag.String("db", "questions.db", "the SQLite database to import the questions to")
+	packs := flag.String("packs", "./packs", "the directory with the question packs")
+	flag.Parse()
+
+	conn, err := question.OpenSQLite(*db)
+	if err != nil {
+		log.Fatalln("unable to open the questions database: ", err)
+	}
+	defer conn.Close()
+
+	loaded, err := question.LoadPacks(*packs)
+	if err != nil {
+		log.Fatalln("unable to load the packs: ", err)
+	}
+	for _, pack := range loaded {
+		if err := question.ImportPack(conn, pack); err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("imported %d questions from pack '%s'", len(pack.Questions), pack.Manifest.Id)
+	}
+}
@@ -2,6 +2,8 @@ package events
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/akselleirv/introspect/game"
 	"github.com/akselleirv/introspect/handler"
 	"github.com/akselleirv/introspect/models"
 	"github.com/akselleirv/introspect/room"
@@ -52,13 +54,64 @@ func Setup(h handler.Handler) func(r room.Roomer) {
 				return
 			}
 
-			playersUpdate, isAllReady := r.Game().GetRoomStatus()
-			b, _ := json.Marshal(models.LobbyRoomUpdate{
-				Event:      "lobby_room_update",
-				Players:    playersUpdate,
-				IsAllReady: isAllReady,
+			r.BroadcastLobbyUpdate("", "")
+		})
+		h.AddEvent("lobby_set_team", func(data map[string]interface{}) {
+			var msg models.SetTeam
+			parseToJson(&data, &msg)
+			if err := r.Game().SetPlayerTeam(msg.Player, msg.Team); err != nil {
+				sendError(r, msg.Player, "set_team_rejected", err)
+				return
+			}
+			r.BroadcastLobbyUpdate(msg.Player, models.TeamsChanged)
+		})
+		h.AddEvent("lobby_balance_teams", func(data map[string]interface{}) {
+			var msg models.GenericEvent
+			parseToJson(&data, &msg)
+			if err := r.Game().BalanceTeams(msg.Player); err != nil {
+				sendError(r, msg.Player, "balance_teams_rejected", err)
+				return
+			}
+			r.BroadcastLobbyUpdate(msg.Player, models.TeamsChanged)
+		})
+		h.AddEvent("get_packs_request", func(data map[string]interface{}) {
+			const event = "get_packs_response"
+			var msg models.GenericEvent
+			parseToJson(&data, &msg)
+			packs, err := r.Game().Packs()
+			if err != nil {
+				sendError(r, msg.Player, event, err)
+				return
+			}
+			b, _ := json.Marshal(models.PacksResponse{
+				Event:    event,
+				Packs:    packs,
+				Selected: r.Game().Settings().Packs,
 			})
-			r.Broadcast(b)
+			r.SendMsg(msg.Player, b)
+		})
+		h.AddEvent("lobby_select_packs", func(data map[string]interface{}) {
+			var msg models.SelectPacks
+			parseToJson(&data, &msg)
+			if err := r.Game().SelectPacks(msg.Player, msg.Packs); err != nil {
+				sendError(r, msg.Player, "select_packs_rejected", err)
+				return
+			}
+			r.BroadcastLobbyUpdate(msg.Player, models.PacksChanged)
+		})
+		h.AddEvent("get_game_snapshot", func(data map[string]interface{}) {
+			var msg models.GenericEvent
+			parseToJson(&data, &msg)
+			r.SendMsg(msg.Player, r.Snapshot())
+		})
+		h.AddEvent("get_game_stats", func(data map[string]interface{}) {
+			var msg models.GenericEvent
+			parseToJson(&data, &msg)
+			b, _ := json.Marshal(models.GameStatsResponse{
+				Event: "game_stats",
+				Stats: r.Game().Stats(),
+			})
+			r.SendMsg(msg.Player, b)
 		})
 		h.AddEvent("get_questions_request", func(data map[string]interface{}) {
 			const event = "get_questions_response"
@@ -66,12 +119,16 @@ func Setup(h handler.Handler) func(r room.Roomer) {
 			parseToJson(&data, &msg)
 
 			questions, err := r.Game().GetQuestions()
-			if err != nil {
-				b, _ := json.Marshal(models.ErrorMsg{
-					Event: event,
-					Error: err.Error(),
+			if errors.Is(err, game.ErrQuestionsExhausted) {
+				b, _ := json.Marshal(models.QuestionsExhausted{
+					Event:        "questions_exhausted",
+					RoundsPlayed: r.Game().GetCurrentDoneQuestion() / game.QuestionsPerRound,
 				})
-				r.SendMsg(msg.Player, b)
+				r.Broadcast(b)
+				return
+			}
+			if err != nil {
+				sendError(r, msg.Player, event, err)
 				return
 			}
 			b, _ := json.Marshal(struct {
@@ -86,85 +143,78 @@ func Setup(h handler.Handler) func(r room.Roomer) {
 		h.AddEvent("register_question_vote", func(data map[string]interface{}) {
 			var msg models.PlayerVotedOnQuestion
 			parseToJson(&data, &msg)
-			var b []byte
-			r.Game().SetVotesFromPlayer(msg)
+			if err := r.Game().SetVotesFromPlayer(msg); err != nil {
+				sendError(r, msg.Player, "question_vote_rejected", err)
+				return
+			}
 			isSelfVoting := r.Game().IsSelfVoting()
 			if isSelfVoting {
-				b, _ = json.Marshal(models.GenericEvent{
-					Event:  "is_self_vote",
-					Player: "",
-				})
+				broadcastSelfVoting(r)
 			} else {
-				b, _ = json.Marshal(models.GenericEvent{
+				b, _ := json.Marshal(models.GenericEvent{
 					Event:  "player_has_question_voted",
 					Player: msg.Player,
 				})
+				r.Broadcast(b)
 			}
-			r.Broadcast(b)
 		})
 		h.AddEvent("register_self_vote", func(data map[string]interface{}) {
-			questionIsDoneMsg := func() ([]byte, error) {
-				return json.Marshal(models.QuestionPointsEvent{
-					Event:           "question_is_done",
-					QuestionPoints:  r.Game().CalculatePointsForCurrentQuestion(),
-					CurrentQuestion: r.Game().GetCurrentDoneQuestion(),
-				})
-			}
 			var msg models.RegisterSelfVote
 			parseToJson(&data, &msg)
-			var b []byte
 
-			r.Game().SetSelfVoteFromPlayer(msg)
+			if err := r.Game().SetSelfVoteFromPlayer(msg); err != nil {
+				sendError(r, msg.Player, "self_vote_rejected", err)
+				return
+			}
 			questionDone, allFinished := r.Game().IsRoundFinished()
 			log.Println(questionDone, allFinished)
-			if allFinished {
-				log.Println("game is done")
-				b, _ = questionIsDoneMsg()
-				log.Println("question is done first res: ", string(b))
-				r.Broadcast(b)
-
-				// here we wait for the last question result to be displayed
-				// then we send the results for all rounds
-				time.Sleep(3 * time.Second)
-				cq := r.Game().GetCurrentDoneQuestion()
-
-				b, _ = json.Marshal(models.PlayersResults{
-					Event:                        "game_is_finished",
-					PlayersResultExceptLastRound: r.Game().CalculatePoints(1, getLastQuestionFromPreviousRound(cq)),
-					PlayersResults:               r.Game().CalculatePoints(1, cq),
-				})
-				log.Println("game is finished: ", string(b))
-			} else if questionDone {
-				log.Println("all players have self voted for current question")
-				b, _ = questionIsDoneMsg()
+			if questionDone {
+				broadcastQuestionIsDone(r, allFinished)
 			} else {
-				b, _ = json.Marshal(models.GenericEvent{
+				b, _ := json.Marshal(models.GenericEvent{
 					Event:  "player_has_self_voted",
 					Player: msg.Player,
 				})
+				r.Broadcast(b)
 			}
-			r.Broadcast(b)
 		})
 		h.AddEvent("next_round", func(data map[string]interface{}) {
 			var msg models.GenericEvent
 			parseToJson(&data, &msg)
+			if r.Game().IsQueued(msg.Player) {
+				// queued players are admitted when the others are ready for the next round
+				return
+			}
 			err := r.Game().SetPlayerReadyForNextRound(msg.Player)
 			if err != nil {
 				log.Println("unable to find player, removing the player from the game...")
 				r.Game().RemovePlayer(msg.Player)
 			}
 
-			if r.Game().IsNextRound() {
-				b, _ := json.Marshal(models.GenericEvent{
-					Event: "all_players_ready_for_next_round",
-				})
-				r.Broadcast(b)
-			}
+			checkNextRound(r)
 		})
 		h.AddEvent("add_question", func(data map[string]interface{}) {
 			var msg models.AddQuestion
 			parseToJson(&data, &msg)
-			r.Game().AddCustomQuestion(msg.Question)
+			approved, err := r.Game().AddCustomQuestion(msg.Player, msg.Question, msg.Language)
+			if err != nil {
+				sendError(r, msg.Player, "add_question_rejected", err)
+				return
+			}
+			if !approved {
+				// the question waits for the host, so only the author and the host are told about it
+				b, _ := json.Marshal(models.GenericEvent{
+					Player: msg.Player,
+					Event:  "custom_question_pending",
+				})
+				r.SendMsg(msg.Player, b)
+				if host := r.Game().Host(); host != msg.Player {
+					r.SendMsg(host, b)
+					sendCustomQuestions(r, host)
+				}
+				sendCustomQuestions(r, msg.Player)
+				return
+			}
 			b, _ := json.Marshal(models.GenericEvent{
 				Player: msg.Player,
 				// TODO: fix this bad code -  I'm lazy
@@ -172,11 +222,175 @@ func Setup(h handler.Handler) func(r room.Roomer) {
 				Action: "player_added_custom_question",
 			})
 			r.Broadcast(b)
+			broadcastCustomQuestions(r)
+		})
+		h.AddEvent("list_custom_questions", func(data map[string]interface{}) {
+			var msg models.GenericEvent
+			parseToJson(&data, &msg)
+			sendCustomQuestions(r, msg.Player)
+		})
+		h.AddEvent("remove_custom_question", func(data map[string]interface{}) {
+			var msg models.ModerateCustomQuestion
+			parseToJson(&data, &msg)
+			if err := r.Game().RemoveCustomQuestion(msg.Player, msg.Id); err != nil {
+				sendError(r, msg.Player, "remove_custom_question_rejected", err)
+				return
+			}
+			broadcastCustomQuestions(r)
+		})
+		h.AddEvent("approve_custom_question", func(data map[string]interface{}) {
+			var msg models.ModerateCustomQuestion
+			parseToJson(&data, &msg)
+			if err := r.Game().ApproveCustomQuestion(msg.Player, msg.Id); err != nil {
+				sendError(r, msg.Player, "approve_custom_question_rejected", err)
+				return
+			}
+			broadcastCustomQuestions(r)
+		})
+
+		// the remaining players should not wait for the player who left,
+		// so we check if the player leaving completed the current phase
+		r.OnPlayerLeft(func(playerName string, d game.Departure) {
+			if !r.Game().IsStarted() {
+				return
+			}
+			b, _ := json.Marshal(models.RoundAdjusted{
+				Event:          "round_adjusted",
+				Player:         playerName,
+				Question:       d.Question,
+				Policy:         models.DiscardVotes,
+				VotesWithdrawn: d.VotesWithdrawn,
+				VotesDiscarded: d.VotesDiscarded,
+			})
+			r.Broadcast(b)
+
+			if d.SelfVotingStarted {
+				broadcastSelfVoting(r)
+			}
+			if questionDone, allFinished := r.Game().IsRoundFinished(); questionDone {
+				broadcastQuestionIsDone(r, allFinished)
+			}
+			checkNextRound(r)
 		})
 	}
 
 }
 
+func broadcastSelfVoting(r room.Roomer) {
+	selfVoting := models.SelfVoting{
+		Event:   "is_self_vote",
+		Player:  "",
+		Scoring: r.Game().Settings().Scoring,
+	}
+	if selfVoting.Scoring == models.PredictionScoring {
+		selfVoting.MaxPrediction = r.Game().MaxPrediction()
+	}
+	b, _ := json.Marshal(selfVoting)
+	r.Broadcast(b)
+}
+
+// broadcastQuestionIsDone sends the points for the question which is done,
+// and the results of the game if it was the last question of the round
+func broadcastQuestionIsDone(r room.Roomer, allFinished bool) {
+	b, _ := json.Marshal(models.QuestionPointsEvent{
+		Event:           "question_is_done",
+		QuestionPoints:  r.Game().CalculatePointsForCurrentQuestion(),
+		CurrentQuestion: r.Game().GetCurrentDoneQuestion(),
+		Scoring:         r.Game().Settings().Scoring,
+		TieRule:         r.Game().Settings().TieRule,
+		Outcome:         r.Game().GetOutcomeForCurrentQuestion(),
+		Voting:          r.Game().Settings().Voting,
+		RevealedVotes:   r.Game().GetVotesForCurrentQuestion(),
+	})
+	if !allFinished {
+		log.Println("all players have self voted for current question")
+		r.Broadcast(b)
+		return
+	}
+
+	log.Println("game is done")
+	log.Println("question is done first res: ", string(b))
+	r.Broadcast(b)
+
+	// here we wait for the last question result to be displayed
+	// then we send the results for all rounds
+	time.Sleep(3 * time.Second)
+	cq := r.Game().GetCurrentDoneQuestion()
+
+	b, _ = json.Marshal(models.PlayersResults{
+		Event:                        "game_is_finished",
+		PlayersResultExceptLastRound: r.Game().CalculatePoints(1, getLastQuestionFromPreviousRound(cq)),
+		PlayersResults:               r.Game().CalculatePoints(1, cq),
+		TeamResultsExceptLastRound:   r.Game().CalculateTeamPoints(1, getLastQuestionFromPreviousRound(cq)),
+		TeamResults:                  r.Game().CalculateTeamPoints(1, cq),
+		Stats:                        r.Game().Stats(),
+	})
+	log.Println("game is finished: ", string(b))
+	r.Broadcast(b)
+}
+
+// sendError tells the player why the event was rejected
+func sendError(r room.Roomer, player, event string, err error) {
+	b, _ := json.Marshal(models.ErrorMsg{
+		Event: event,
+		Error: err.Error(),
+	})
+	r.SendMsg(player, b)
+}
+
+// sendCustomQuestions sends the custom questions the player may see, the questions waiting for approval are sent to the host and their author
+func sendCustomQuestions(r room.Roomer, player string) {
+	b, _ := json.Marshal(models.CustomQuestionsResponse{
+		Event:     "custom_questions",
+		Questions: visibleCustomQuestions(r, player),
+	})
+	r.SendMsg(player, b)
+}
+
+// broadcastCustomQuestions broadcasts the approved custom questions, the host gets the questions waiting for approval as well
+func broadcastCustomQuestions(r room.Roomer) {
+	b, _ := json.Marshal(models.CustomQuestionsResponse{
+		Event:     "custom_questions",
+		Questions: visibleCustomQuestions(r, ""),
+	})
+	r.Broadcast(b)
+	if host := r.Game().Host(); host != "" {
+		sendCustomQuestions(r, host)
+	}
+}
+
+// visibleCustomQuestions returns the approved custom questions, the questions waiting for approval are only
+// visible to the host and the author
+func visibleCustomQuestions(r room.Roomer, player string) []models.CustomQuestion {
+	isHost := player != "" && player == r.Game().Host()
+	questions := []models.CustomQuestion{}
+	for _, q := range r.Game().CustomQuestions() {
+		if q.Approved || isHost || (player != "" && q.Author == player) {
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
+
+// checkNextRound starts the next round if all players are ready for it
+func checkNextRound(r room.Roomer) {
+	if !r.Game().IsNextRound() {
+		return
+	}
+	b, _ := json.Marshal(models.GenericEvent{
+		Event: "all_players_ready_for_next_round",
+	})
+	r.Broadcast(b)
+
+	if admitted := r.Game().AdmitQueuedPlayers(); len(admitted) > 0 {
+		b, _ = json.Marshal(models.PlayersJoinedGame{
+			Event:   "players_joined_game",
+			Players: admitted,
+		})
+		r.Broadcast(b)
+	}
+}
+
 func getLastQuestionFromPreviousRound(currentQuestion int) int {
 	return currentQuestion - 4
 }
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"log"
+	"sort"
+)
+
+// ErrQuestionsExhausted is returned when there are not enough new questions for another round
+var ErrQuestionsExhausted = errors.New("there are no more questions")
+
+// MaxRounds returns the number of rounds the game can play before it runs out of new questions,
+// the rounds which are played are included. With RecycleQuestions the game can play more rounds.
+func (g *Game) MaxRounds() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	rounds := len(g.questions) / QuestionsPerRound
+	remaining, err := g.questionStore.Remaining(getQuestionIds(g.questions))
+	if err != nil {
+		log.Printf("unable to count the remaining questions: %s", err)
+		return rounds
+	}
+	// each round takes four questions from the store, the custom questions are played instead of some of them
+	custom := len(g.customQuestions)
+	for remaining >= question.NumberOfQuestionsToFind {
+		c := custom
+		if c > QuestionsPerRound {
+			c = QuestionsPerRound
+		}
+		custom -= c
+		remaining -= QuestionsPerRound - c
+		rounds++
+	}
+	return rounds
+}
+
+// nextQuestions returns the questions for the next round from the store. When there are no new questions
+// the questions played longest ago are served again with RecycleQuestions, g.mu must be held.
+func (g *Game) nextQuestions() ([]models.Question, error) {
+	used := getQuestionIds(g.questions)
+	questions, err := g.questionStore.GetFourUnique(used)
+	if err == nil {
+		return questions, nil
+	}
+	remaining, rerr := g.questionStore.Remaining(used)
+	if rerr != nil || remaining >= question.NumberOfQuestionsToFind {
+		return nil, err
+	}
+	if !g.settings.RecycleQuestions {
+		return nil, fmt.Errorf("%w: %s", ErrQuestionsExhausted, err)
+	}
+	return g.recycleQuestions(used)
+}
+
+// recycleQuestions serves the questions played longest ago again, as few as needed are recycled. g.mu must be held.
+func (g *Game) recycleQuestions(used []string) ([]models.Question, error) {
+	lru := leastRecentlyUsed(used)
+	// the more of the least recently used ids which are dropped, the more questions are remaining
+	drop := sort.Search(len(lru)+1, func(drop int) bool {
+		remaining, err := g.questionStore.Remaining(lru[drop:])
+		return err == nil && remaining >= question.NumberOfQuestionsToFind
+	})
+	if drop > len(lru) {
+		return nil, fmt.Errorf("%w: the packs have less than %d questions", ErrQuestionsExhausted, question.NumberOfQuestionsToFind)
+	}
+	log.Printf("recycling the %d questions played longest ago", drop)
+	return g.questionStore.GetFourUnique(lru[drop:])
+}
+
+// leastRecentlyUsed returns each id once, ordered by when it was last used with the least recently used first
+func leastRecentlyUsed(ids []string) []string {
+	last := make(map[string]int)
+	for i, id := range ids {
+		last[id] = i
+	}
+	var lru []string
+	for i, id := range ids {
+		if last[id] == i {
+			lru = append(lru, id)
+		}
+	}
+	return lru
+}
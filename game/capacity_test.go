@@ -0,0 +1,71 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestMaxRounds(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+	assert.Equal(t, 2, g.MaxRounds(), "the test pack has eight questions")
+
+	for i := 0; i < 5; i++ {
+		g.AddCustomQuestion(p1, "custom", "")
+	}
+	assert.Equal(t, 3, g.MaxRounds(), "custom questions are played instead of questions from the store")
+
+	g = NewGame(TestQuestions, models.RoomSettings{})
+	assert.NoError(t, g.loadQuestions())
+	assert.Equal(t, 2, g.MaxRounds(), "the rounds which are loaded are included")
+}
+
+func TestRecycleQuestions(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{RecycleQuestions: true})
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, g.loadQuestions())
+	}
+	assert.ElementsMatch(t, getQuestionIds(g.questions[:4]), getQuestionIds(g.questions[8:]),
+		"the questions played longest ago are recycled")
+	assert.NoError(t, g.loadQuestions())
+	assert.ElementsMatch(t, getQuestionIds(g.questions[4:8]), getQuestionIds(g.questions[12:]))
+
+	g = NewGame(TestQuestions, models.RoomSettings{})
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, g.loadQuestions())
+	}
+	assert.ErrorIs(t, g.loadQuestions(), ErrQuestionsExhausted)
+}
+
+func TestLeastRecentlyUsed(t *testing.T) {
+	assert.Equal(t, []string{"b", "c", "a"}, leastRecentlyUsed([]string{"a", "b", "a", "c", "a"}))
+	assert.Empty(t, leastRecentlyUsed(nil))
+}
+
+func TestCustomQuestionsAreKeptWhenTheQuestionsAreExhausted(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, g.loadQuestions())
+	}
+	_, err := g.AddCustomQuestion(p1, "custom", "")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, g.loadQuestions(), ErrQuestionsExhausted)
+	assert.Len(t, g.CustomQuestions(), 1, "the custom question is played if the game gets more questions")
+}
+
+func TestMaxRoundsConcurrently(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	assert.NoError(t, g.loadQuestions())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, 2, g.MaxRounds())
+		}()
+	}
+	wg.Wait()
+}
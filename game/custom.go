@@ -0,0 +1,118 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"github.com/google/uuid"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxCustomQuestionLength is the most characters a custom question can have
+const MaxCustomQuestionLength = 200
+
+var ErrUnknownCustomQuestion = errors.New("unknown custom question")
+
+// AddCustomQuestion adds a question written by the player, it returns true if the question is approved.
+// The question is written in the language, or in the language of the room when it is empty.
+func (g *Game) AddCustomQuestion(author, text, language string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, exist := g.players[author]; !exist && !g.isQueued(author) {
+		return false, fmt.Errorf("player '%s' is not in the game", author)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return false, errors.New("the question can not be empty")
+	}
+	if utf8.RuneCountInString(text) > MaxCustomQuestionLength {
+		return false, fmt.Errorf("the question can not be longer than %d characters", MaxCustomQuestionLength)
+	}
+	if language == "" {
+		language = g.settings.Language
+	}
+	if language == "" {
+		language = question.DefaultLanguage
+	}
+	if err := question.ValidateLanguage(language); err != nil {
+		return false, err
+	}
+
+	q := models.Question{
+		Id:       uuid.NewString(),
+		Question: models.QuestionTranslations{language: text},
+		Text:     text,
+		Language: language,
+		Type:     models.MostLikelyQuestion,
+		Author:   author,
+	}
+	if g.settings.ApproveCustomQuestions && author != g.host {
+		g.pendingQuestions = append(g.pendingQuestions, q)
+		return false, nil
+	}
+	g.customQuestions = append(g.customQuestions, q)
+	return true, nil
+}
+
+// CustomQuestions returns the custom questions which are not played, the approved questions are first
+func (g *Game) CustomQuestions() []models.CustomQuestion {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	questions := []models.CustomQuestion{}
+	for _, q := range g.customQuestions {
+		questions = append(questions, customQuestion(q, true))
+	}
+	for _, q := range g.pendingQuestions {
+		questions = append(questions, customQuestion(q, false))
+	}
+	return questions
+}
+
+func customQuestion(q models.Question, approved bool) models.CustomQuestion {
+	return models.CustomQuestion{Id: q.Id, Text: q.Text, Language: q.Language, Author: q.Author, Approved: approved}
+}
+
+// RemoveCustomQuestion removes a question which is not played, only the author or the host can remove it
+func (g *Game) RemoveCustomQuestion(playerName, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, list := range []*[]models.Question{&g.customQuestions, &g.pendingQuestions} {
+		i := indexOfQuestion(*list, id)
+		if i < 0 {
+			continue
+		}
+		if playerName != (*list)[i].Author && playerName != g.host {
+			return errors.New("only the author or the host can remove the question")
+		}
+		*list = append((*list)[:i], (*list)[i+1:]...)
+		return nil
+	}
+	return ErrUnknownCustomQuestion
+}
+
+// ApproveCustomQuestion lets the host approve a question, it is played after the approved questions before it
+func (g *Game) ApproveCustomQuestion(playerName, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if playerName != g.host {
+		return ErrNotHost
+	}
+	i := indexOfQuestion(g.pendingQuestions, id)
+	if i < 0 {
+		return ErrUnknownCustomQuestion
+	}
+	g.customQuestions = append(g.customQuestions, g.pendingQuestions[i])
+	g.pendingQuestions = append(g.pendingQuestions[:i], g.pendingQuestions[i+1:]...)
+	return nil
+}
+
+func indexOfQuestion(questions []models.Question, id string) int {
+	for i, q := range questions {
+		if q.Id == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,75 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAddCustomQuestion(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{Language: "nb-NO"})
+	g.AddPlayer(p1)
+
+	approved, err := g.AddCustomQuestion(p1, "  Hvem kommer for sent?  ", "")
+	assert.NoError(t, err)
+	assert.True(t, approved)
+	q := g.customQuestions[0]
+	assert.Equal(t, "Hvem kommer for sent?", q.Text)
+	assert.Equal(t, "nb-NO", q.Language, "the question is written in the language of the room")
+
+	_, err = g.AddCustomQuestion(p1, "Who is late?", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, models.QuestionTranslations{"en": "Who is late?"}, g.customQuestions[1].Question)
+
+	_, err = g.AddCustomQuestion(p2, "Who is late?", "")
+	assert.Error(t, err, "only players in the game can add questions")
+	_, err = g.AddCustomQuestion(p1, " ", "")
+	assert.Error(t, err)
+	_, err = g.AddCustomQuestion(p1, strings.Repeat("a", MaxCustomQuestionLength+1), "")
+	assert.Error(t, err)
+	_, err = g.AddCustomQuestion(p1, "Who is late?", "english")
+	assert.Error(t, err)
+}
+
+func TestApproveCustomQuestion(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{ApproveCustomQuestions: true})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+
+	approved, err := g.AddCustomQuestion(p1, "from the host", "")
+	assert.NoError(t, err)
+	assert.True(t, approved, "the questions of the host do not need approval")
+	approved, err = g.AddCustomQuestion(p2, "from a player", "")
+	assert.NoError(t, err)
+	assert.False(t, approved)
+
+	questions := g.CustomQuestions()
+	assert.Len(t, questions, 2)
+	assert.True(t, questions[0].Approved)
+	assert.False(t, questions[1].Approved)
+	assert.Equal(t, p2, questions[1].Author)
+	assert.Len(t, g.customQuestions, 1, "only approved questions are played")
+
+	assert.ErrorIs(t, g.ApproveCustomQuestion(p2, questions[1].Id), ErrNotHost)
+	assert.ErrorIs(t, g.ApproveCustomQuestion(p1, "unknown"), ErrUnknownCustomQuestion)
+	assert.NoError(t, g.ApproveCustomQuestion(p1, questions[1].Id))
+	assert.Len(t, g.customQuestions, 2)
+	assert.Empty(t, g.pendingQuestions)
+}
+
+func TestRemoveCustomQuestion(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{ApproveCustomQuestions: true})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+	g.AddPlayer(p3)
+	g.AddCustomQuestion(p2, "first", "")
+	g.AddCustomQuestion(p2, "second", "")
+	questions := g.CustomQuestions()
+
+	assert.Error(t, g.RemoveCustomQuestion(p3, questions[0].Id), "only the author or the host can remove a question")
+	assert.NoError(t, g.RemoveCustomQuestion(p2, questions[0].Id))
+	assert.NoError(t, g.RemoveCustomQuestion(p1, questions[1].Id))
+	assert.ErrorIs(t, g.RemoveCustomQuestion(p1, questions[1].Id), ErrUnknownCustomQuestion)
+	assert.Empty(t, g.CustomQuestions())
+}
@@ -1,15 +1,21 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	"github.com/akselleirv/introspect/models"
 	"github.com/akselleirv/introspect/question"
 	"log"
+	"math/rand"
 	"sync"
 )
 
 const (
-	MaxVotesPerQuestion = 2
+	// MinVotesPerQuestion and MaxVotesPerQuestion limits the votes each player gives in a most likely question,
+	// between the limits the players get one vote for every PlayersPerVote players in the game
+	MinVotesPerQuestion = 2
+	MaxVotesPerQuestion = 5
+	PlayersPerVote      = 3
 	QuestionsPerRound   = 4
 	FirstQuestionNumber = 1
 )
@@ -20,63 +26,143 @@ const (
 	MostVoted  SelfVote = "Most Voted"
 	Neutral    SelfVote = "Neutral"
 	LeastVoted SelfVote = "Least Voted"
-
-	MostVotedPoints  = 3
-	NeutralPoints    = 1
-	LeastVotedPoints = 3
-	WrongVotedPoints = 0
 )
 
 type Gamer interface {
 	SetPlayerReadyToStartGame(playerName string) error
 
-	// return true if all players are readyToStartGame
+	// return true if all players are readyToStartGame and the minimum number of players is met,
 	// and a slice of players who are readyToStartGame
 	IsPlayersReady() (bool, []string)
+	Settings() models.RoomSettings
+	// AddPlayer adds the player to the game, or queues the player
+	// for the next round if the game has already started
 	AddPlayer(playerName string) bool
-	RemovePlayer(playerName string)
+	IsQueued(playerName string) bool
+	IsStarted() bool
+	// AdmitQueuedPlayers adds the queued players to the game, it must only be called between rounds.
+	// It returns the names of the admitted players.
+	AdmitQueuedPlayers() []string
+	// RemovePlayer removes the player and resolves the votes of the current question
+	// cast by and for the player, see Departure
+	RemovePlayer(playerName string) Departure
 	GetRoomStatus() ([]models.PlayerUpdate, bool)
 
 	// GetQuestions will return four question that
-	// the room has not yet received
+	// the room has not yet received, it returns ErrQuestionsExhausted when there are no more questions
 	GetQuestions() ([]models.Question, error)
+	// MaxRounds returns the number of rounds the game can play before it runs out of new questions
+	MaxRounds() int
 	GetCurrentDoneQuestion() int
-	SetVotesFromPlayer(question models.PlayerVotedOnQuestion)
+	SetVotesFromPlayer(question models.PlayerVotedOnQuestion) error
 	IsSelfVoting() bool
-	SetSelfVoteFromPlayer(vote models.RegisterSelfVote)
+	// SetSelfVoteFromPlayer returns an error if the self vote does not fit the scoring of the game
+	SetSelfVoteFromPlayer(vote models.RegisterSelfVote) error
+	// MaxPrediction returns the most votes a player can receive for the current question
+	MaxPrediction() int
 	IsRoundFinished() (bool, bool)
 
 	CalculatePointsForCurrentQuestion() models.QuestionPoints
+	// GetOutcomeForCurrentQuestion returns how the last done question was scored
+	GetOutcomeForCurrentQuestion() models.ScoringOutcome
+	// GetVotesForCurrentQuestion returns who voted for whom in the last done question,
+	// it returns nil unless the game uses RevealedVoting
+	GetVotesForCurrentQuestion() *models.RevealedVotes
 	CalculatePoints(from, to int) []models.PointsEntrySimple
+	// CalculateTeamPoints returns nil when the game is played without teams
+	CalculateTeamPoints(from, to int) []models.TeamPointsEntry
+
+	Teams() []string
+	SetPlayerTeam(playerName, team string) error
+	BalanceTeams(playerName string) error
 
 	SetPlayerReadyForNextRound(playerName string) error
 	IsNextRound() bool
 
-	AddCustomQuestion(question string)
+	// AddCustomQuestion adds a question written by the player, it returns true if the question is approved.
+	// With ApproveCustomQuestions the questions of other players than the host wait for the host to approve them.
+	AddCustomQuestion(author, text, language string) (bool, error)
+	CustomQuestions() []models.CustomQuestion
+	// RemoveCustomQuestion removes a question which is not played, only the author or the host can remove it
+	RemoveCustomQuestion(playerName, id string) error
+	// ApproveCustomQuestion returns ErrNotHost if the player is not the host
+	ApproveCustomQuestion(playerName, id string) error
+
+	// Host returns the player who can change the room in the lobby
+	Host() string
+	Packs() ([]models.PackManifest, error)
+	// SelectPacks returns ErrNotHost if the player is not the host
+	SelectPacks(playerName string, packs []string) error
+
+	// Stats returns the statistics and awards of the questions which are done
+	Stats() models.GameStats
+
+	// Snapshot returns the current state of the game, it is used by clients joining a game in progress
+	Snapshot() models.GameSnapshot
 }
 
 type Game struct {
-	players         map[string]*player
+	players map[string]*player
+	// queued players joined after the game started and will play from the next round
+	queued []string
+	// host is the player who can change the room in the lobby, it is the first player who joined
+	host string
+	// name is the name of the room, it is the group of the game with RoomHistory
+	name            string
+	started         bool
 	currentQuestion int
+	// ledger has a record for every question number which has been played
+	ledger          map[int]*questionRecord
 	customQuestions []models.Question
-	questions       []models.Question
-	questionStore   question.Questioner
-	mu              sync.RWMutex
+	// pendingQuestions are custom questions waiting for the host to approve them
+	pendingQuestions []models.Question
+	questions        []models.Question
+	questionStore    question.Questioner
+	questionSource   question.Source
+	settings         models.RoomSettings
+	scorer           Scorer
+	// rand is seeded with the seed in the settings, so a game can be played again with the same choices
+	rand *rand.Rand
+	mu   sync.RWMutex
 }
 
 type player struct {
 	readyToStartGame  bool
 	readyForNextRound bool
-	// a map of question number and number of votes the player have received
-	votes     map[int]int
-	selfVotes map[int]SelfVote
+	team              string
+}
+
+// Departure describes how the current question was adjusted when a player left.
+// Completed questions are not changed. For the current question the votes cast by the player are withdrawn,
+// and the votes cast for the player are discarded without letting the voters vote again.
+type Departure struct {
+	// Question is the question in progress when the player left
+	Question       int
+	VotesWithdrawn int
+	VotesDiscarded int
+	// SelfVotingStarted is true if the player was the last one the others waited for to finish voting
+	SelfVotingStarted bool
 }
 
-func NewGame(questionFilePath string) Game {
+// NewGame creates a game using the given settings, zero values in the settings are replaced by defaults.
+// The settings are expected to be validated by ValidateSettings.
+func NewGame(questionSource question.Source, settings models.RoomSettings) Game {
+	settings = withDefaults(settings)
+	scorer, err := NewScorer(settings)
+	if err != nil {
+		log.Printf("unable to use scoring '%s', using '%s' instead: %s", settings.Scoring, models.SelfAssessmentScoring, err)
+		scorer = SelfAssessmentScorer{TieRule: settings.TieRule}
+	}
+	log.Printf("creating game with seed %d", settings.Seed)
 	return Game{
 		players:         make(map[string]*player),
 		currentQuestion: 1,
-		questionStore:   question.NewStore(questionFilePath),
+		ledger:          make(map[int]*questionRecord),
+		questionStore:   newQuestionStore(questionSource, settings),
+		questionSource:  questionSource,
+		settings:        settings,
+		scorer:          scorer,
+		rand:            rand.New(rand.NewSource(settings.Seed)),
 		mu:              sync.RWMutex{},
 	}
 }
@@ -93,6 +179,7 @@ func (g *Game) SetPlayerReadyToStartGame(playerName string) error {
 	if p, exist := g.players[playerName]; exist {
 		p.readyToStartGame = true
 		log.Println("setting player readyToStartGame: ", playerName)
+		g.startIfAllReady()
 		return nil
 	} else {
 		err := fmt.Errorf("unable to find a player with the name '%s', when setting readyToStartGame status to true", playerName)
@@ -113,59 +200,169 @@ func (g *Game) IsPlayersReady() (bool, []string) {
 		}
 	}
 
-	return len(readyPlayers) == len(g.players), readyPlayers
+	return len(readyPlayers) == len(g.players) && g.hasEnoughPlayers(), readyPlayers
+}
+
+func (g *Game) Settings() models.RoomSettings {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.settings
+}
+
+func (g *Game) hasEnoughPlayers() bool {
+	return len(g.players) >= g.settings.MinPlayers
 }
 
+// startIfAllReady starts the game once every player in the lobby is ready, g.mu must be held
+func (g *Game) startIfAllReady() {
+	if g.started || !g.isAllReadyToStartGame() {
+		return
+	}
+	g.started = true
+	g.useHistory()
+}
+
+func (g *Game) isAllReadyToStartGame() bool {
+	for _, p := range g.players {
+		if !p.readyToStartGame {
+			return false
+		}
+	}
+	return g.hasEnoughPlayers()
+}
+
+// AddPlayer returns false if the name is taken or the game is full
 func (g *Game) AddPlayer(playerName string) bool {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	if _, exists := g.players[playerName]; exists {
+	if _, exists := g.players[playerName]; exists || g.isQueued(playerName) {
 		return false
 		// TODO: handle error - that name is taken
+	} else if len(g.players)+len(g.queued) >= g.settings.MaxPlayers {
+		return false
+	} else if g.started {
+		log.Printf("game has started, player '%s' is queued for the next round", playerName)
+		g.queued = append(g.queued, playerName)
+		return true
 	} else {
-		g.players[playerName] = &player{readyToStartGame: false, votes: make(map[int]int), selfVotes: map[int]SelfVote{}}
+		g.players[playerName] = &player{readyToStartGame: false, team: g.smallestTeam()}
+		if g.host == "" {
+			g.host = playerName
+		}
 		return true
 	}
 }
 
-func (g *Game) RemovePlayer(playerName string) {
+func (g *Game) IsQueued(playerName string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isQueued(playerName)
+}
+
+func (g *Game) isQueued(playerName string) bool {
+	for _, name := range g.queued {
+		if name == playerName {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) AdmitQueuedPlayers() []string {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	admitted := g.queued
+	for _, name := range admitted {
+		// the game has already started, so the player does not need to ready up in the lobby
+		g.players[name] = &player{readyToStartGame: true, team: g.smallestTeam()}
+		if g.host == "" {
+			g.host = name
+		}
+		log.Printf("admitted queued player '%s' from question %d", name, g.currentQuestion)
+	}
+	g.queued = nil
+	return admitted
+}
+
+func (g *Game) RemovePlayer(playerName string) Departure {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d := Departure{Question: g.currentQuestion}
+	for i, name := range g.queued {
+		if name == playerName {
+			g.queued = append(g.queued[:i], g.queued[i+1:]...)
+			break
+		}
+	}
+	if _, exist := g.players[playerName]; !exist {
+		return d
+	}
+	wasSelfVoting := g.isSelfVoting()
+
+	record := g.currentRecord()
+	d.VotesWithdrawn = record.withdrawVotesFrom(playerName)
+	d.VotesDiscarded = record.discardVotesFor(playerName)
+	delete(record.selfVotes, playerName)
 	delete(g.players, playerName)
+	if g.host == playerName {
+		g.host = g.nextHost()
+	}
+	// the player who left may have been the last one the others were waiting for in the lobby
+	g.startIfAllReady()
+
+	d.SelfVotingStarted = !wasSelfVoting && g.isSelfVoting()
+	log.Printf("removed player '%s' from question %d: %d votes withdrawn and %d votes discarded", playerName, d.Question, d.VotesWithdrawn, d.VotesDiscarded)
+	return d
 }
 
 // GetRoomStatus will get the status of the players
 // by returning a map of clientName and a boolean if the player is readyToStartGame or not
-// and a boolean which is true when all players are readyToStartGame
+// and a boolean which is true when all players are readyToStartGame and there are enough players to start
 func (g *Game) GetRoomStatus() ([]models.PlayerUpdate, bool) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
 	var playersUpdate []models.PlayerUpdate
-	isAllReady := true
 	for name, player := range g.players {
-		if !player.readyToStartGame {
-			isAllReady = false
-		}
 		playersUpdate = append(playersUpdate, models.PlayerUpdate{
 			Name:    name,
 			IsReady: player.readyToStartGame,
+			Team:    player.team,
+			IsHost:  name == g.host,
+		})
+	}
+	for _, name := range g.queued {
+		playersUpdate = append(playersUpdate, models.PlayerUpdate{
+			Name:     name,
+			IsQueued: true,
 		})
 	}
-	return playersUpdate, isAllReady
+	return playersUpdate, g.isAllReadyToStartGame()
 }
 
 // loadQuestions will make the call to the question database and set it question on the Game struct
 func (g *Game) loadQuestions() error {
-	result := make([]models.Question, QuestionsPerRound)
-	if g.isCustomQuestions() {
-		result = g.getCustomQuestions()
-	}
-	newQuestions, err := g.questionStore.GetFourUnique(getQuestionIds(g.questions))
+	// the custom questions are only taken off the queue when the store has questions for the round,
+	// so they are not lost when the questions are exhausted
+	newQuestions, err := g.nextQuestions()
 	if err != nil {
 		return err
 	}
+	var result []models.Question
+	if g.isCustomQuestions() {
+		result = g.getCustomQuestions()
+	}
+	custom := len(result)
 	result = append(result, newQuestions...)
+	if custom < QuestionsPerRound {
+		g.recordPlayed(newQuestions[:QuestionsPerRound-custom])
+	}
+	for i := range result {
+		// the votes are decided when the questions are loaded, so they do not change during the round
+		if questionType(result[i]) == models.MostLikelyQuestion && result[i].VotesPerPlayer == 0 {
+			result[i].VotesPerPlayer = votesPerPlayer(len(g.players))
+		}
+	}
 	g.questions = append(g.questions, result[0:QuestionsPerRound]...)
 	return nil
 }
@@ -176,7 +373,7 @@ func (g *Game) isCustomQuestions() bool {
 func (g *Game) getCustomQuestions() []models.Question {
 	result := make([]models.Question, QuestionsPerRound)
 	var i int
-	for i = 0; i < len(g.customQuestions); i++ {
+	for i = 0; i < len(g.customQuestions) && i < QuestionsPerRound; i++ {
 		result[i] = g.customQuestions[i]
 	}
 	g.customQuestions = g.customQuestions[i:]
@@ -196,9 +393,8 @@ func (g *Game) GetQuestions() ([]models.Question, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	if len(g.questions) <= g.currentQuestion {
-		err := g.loadQuestions()
-		if err != nil {
-			return nil, fmt.Errorf("no more questions: %w", err)
+		if err := g.loadQuestions(); err != nil {
+			return nil, err
 		}
 	}
 
@@ -206,73 +402,148 @@ func (g *Game) GetQuestions() ([]models.Question, error) {
 	return lastFourQuestions, nil
 }
 
-// SetVotesFromPlayer register the vote from the player
-func (g *Game) SetVotesFromPlayer(votes models.PlayerVotedOnQuestion) {
+// SetVotesFromPlayer register the vote from the player,
+// a player can only vote once per question and the votes must fit the type of the question
+func (g *Game) SetVotesFromPlayer(votes models.PlayerVotedOnQuestion) error {
 	g.mu.Lock()
-	v1, v2 := votes.Votes[0], votes.Votes[1]
-	if p, exist := g.players[v1.PlayerWhoReceivedTheVote]; exist {
-		p.votes[g.currentQuestion]++
+	defer g.mu.Unlock()
+	record := g.currentRecord()
+	if _, exist := g.players[votes.Player]; !exist || record.hasVoted(votes.Player) {
+		log.Printf("ignoring votes from player '%s' for question %d", votes.Player, g.currentQuestion)
+		return fmt.Errorf("player '%s' is not in the game or has already voted", votes.Player)
 	}
-	if p, exist := g.players[v2.PlayerWhoReceivedTheVote]; exist {
-		p.votes[g.currentQuestion]++
+	record.question = g.question(g.currentQuestion)
+	return g.castBallot(record, votes)
+}
+
+// currentRecord returns the ledger record of the current question and creates it if it is missing,
+// g.mu must be held for writing
+func (g *Game) currentRecord() *questionRecord {
+	record, ok := g.ledger[g.currentQuestion]
+	if !ok {
+		record = newQuestionRecord()
+		g.ledger[g.currentQuestion] = record
 	}
-	g.mu.Unlock()
+	return record
 }
 
+// IsSelfVoting returns true when all players have voted on the current question
 func (g *Game) IsSelfVoting() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	var totalVotes int
-	for _, p := range g.players {
-		for _, votes := range p.votes {
-			totalVotes += votes
+	return g.isSelfVoting()
+}
+
+func (g *Game) isSelfVoting() bool {
+	// the record is only looked up since this is also called with the read lock,
+	// a missing record means nobody has voted yet
+	record, ok := g.ledger[g.currentQuestion]
+	if len(g.players) == 0 || !ok {
+		return false
+	}
+	for name := range g.players {
+		if !record.hasVoted(name) {
+			return false
 		}
 	}
-	expectedTotalVotes := g.currentQuestion * MaxVotesPerQuestion * len(g.players)
-	return expectedTotalVotes == totalVotes
+	return true
 }
 
-func (g *Game) SetSelfVoteFromPlayer(vote models.RegisterSelfVote) {
+func (g *Game) SetSelfVoteFromPlayer(vote models.RegisterSelfVote) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	if p, exist := g.players[vote.Player]; exist {
-		p.selfVotes[g.currentQuestion] = SelfVote(vote.Choice)
+	if _, exist := g.players[vote.Player]; !exist {
+		return fmt.Errorf("unable to find a player with the name '%s', when setting self vote", vote.Player)
 	}
+
+	record := g.currentRecord()
+	if g.settings.Scoring == models.PredictionScoring {
+		if vote.Prediction == nil {
+			return errors.New("a prediction of the number of votes is required")
+		}
+		if *vote.Prediction < 0 || *vote.Prediction > g.maxPrediction() {
+			return fmt.Errorf("prediction must be between 0 and %d, got %d", g.maxPrediction(), *vote.Prediction)
+		}
+		record.predictions[vote.Player] = *vote.Prediction
+		return nil
+	}
+
+	switch choice := SelfVote(vote.Choice); choice {
+	case MostVoted, Neutral, LeastVoted:
+		record.selfVotes[vote.Player] = choice
+		return nil
+	default:
+		return fmt.Errorf("unknown self vote choice '%s'", vote.Choice)
+	}
+}
+
+func (g *Game) MaxPrediction() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.maxPrediction()
+}
+
+// maxPrediction is the number of votes a player receives if every player gives all their votes to the player
+func (g *Game) maxPrediction() int {
+	return maxVotesReceived(g.question(g.currentQuestion), len(g.players))
 }
 
 // HaveAllPlayersSelfVoted check if all the players have issued their self vote.
 // It then returns two booleans.
 // First is true if all players have issued their self vote.
 // Second is true if players have self voted AND it is the last round.
+// When the question is done, the points are awarded and stored in the ledger.
 func (g *Game) IsRoundFinished() (bool, bool) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	record := g.currentRecord()
 	var totalSelfVotesForRound int
-	for _, p := range g.players {
-		if p.selfVotes[g.currentQuestion] != "" {
+	for name := range g.players {
+		if record.hasSelfVoted(name) {
 			totalSelfVotesForRound++
 		}
 	}
 
-	questionDone := len(g.players) == totalSelfVotesForRound
+	questionDone := len(g.players) > 0 && len(g.players) == totalSelfVotesForRound
 	roundFinished := questionDone && g.currentQuestion%QuestionsPerRound == 0
 	log.Printf("current round: '%d' - questionDone: '%t' - roundFinished: '%t' ", g.currentQuestion, questionDone, roundFinished)
 
 	if questionDone {
+		stats := g.questionStats(g.currentQuestion, record)
+		record.points = g.scorer.Score(stats)
+		record.outcome = stats.Outcome
+		record.teams = make(map[string]string)
+		for name, p := range g.players {
+			record.teams[name] = p.team
+		}
+		record.teamBonus = g.teamBonus(record)
+		record.done = true
 		g.currentQuestion++
 	}
 	return questionDone, roundFinished
 }
 
-// CalculatePoints calculates points from the given range of questions
+// CalculatePoints calculates points from the given range of questions.
+// Players who did not take part in all the questions, are still in the standings.
 func (g *Game) CalculatePoints(from, to int) []models.PointsEntrySimple {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	totalPoints := make(map[string]int)
+	if from <= to {
+		for name := range g.players {
+			totalPoints[name] = 0
+		}
+	}
 	for i := from; i <= to; i++ {
-		qp := getPointsForQuestion(g.players, i)
-		for _, entry := range qp {
-			totalPoints[entry.Player] += entry.Points
+		record, ok := g.ledger[i]
+		if !ok || !record.done {
+			continue
+		}
+		for _, entry := range record.points {
+			// players who have left the game are not in the standings
+			if _, exist := g.players[entry.Player]; exist {
+				totalPoints[entry.Player] += entry.Points
+			}
 		}
 	}
 	var pes []models.PointsEntrySimple
@@ -288,77 +559,79 @@ func (g *Game) CalculatePoints(from, to int) []models.PointsEntrySimple {
 func (g *Game) CalculatePointsForCurrentQuestion() models.QuestionPoints {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	return getPointsForQuestion(g.players, g.currentQuestion-1)
-}
-
-type playerStat struct {
-	name     string
-	votes    int
-	selfVote SelfVote
-}
-
-func getPointsForQuestion(players map[string]*player, currentRound int) models.QuestionPoints {
-	vs := getPlayerStats(players, currentRound)
-	min, max := findMinAndMaxVotes(vs)
-	leastVoted, neutral, mostVoted := findPlayerPositions(vs, min, max)
-	return givePoints(leastVoted, neutral, mostVoted)
+	if record, ok := g.ledger[g.currentQuestion-1]; ok {
+		return record.points
+	}
+	return nil
 }
 
-// getPlayerStats converts the player map for the currentRound into a slice
-func getPlayerStats(players map[string]*player, currentRound int) []playerStat {
-	var ps []playerStat
-	for n, p := range players {
-		ps = append(ps, playerStat{name: n, votes: p.votes[currentRound], selfVote: p.selfVotes[currentRound]})
+func (g *Game) GetOutcomeForCurrentQuestion() models.ScoringOutcome {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if record, ok := g.ledger[g.currentQuestion-1]; ok {
+		return record.outcome
 	}
-	return ps
+	return ""
 }
 
-func findMinAndMaxVotes(playerStats []playerStat) (min, max int) {
-	min = playerStats[0].votes
-	max = playerStats[0].votes
-	for _, playerStat := range playerStats {
-		if playerStat.votes < min {
-			min = playerStat.votes
+func (g *Game) GetVotesForCurrentQuestion() *models.RevealedVotes {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	record, ok := g.ledger[g.currentQuestion-1]
+	if !ok || g.settings.Voting != models.RevealedVoting {
+		return nil
+	}
+	revealed := &models.RevealedVotes{}
+	switch questionType(record.question) {
+	case models.RatingQuestion:
+		revealed.Ratings = make(map[string]map[string]int, len(record.ratings))
+		for voter, ratings := range record.ratings {
+			revealed.Ratings[voter] = make(map[string]int, len(ratings))
+			for name, rating := range ratings {
+				revealed.Ratings[voter][name] = rating
+			}
 		}
-		if playerStat.votes > max {
-			max = playerStat.votes
+	case models.ThisOrThatQuestion:
+		revealed.Options = make(map[string]int, len(record.options))
+		for voter, option := range record.options {
+			revealed.Options[voter] = option
 		}
-	}
-	return min, max
-}
-func findPlayerPositions(playerStats []playerStat, min, max int) (leastVoted, neutral, mostVoted []playerStat) {
-	for _, playerStat := range playerStats {
-		if playerStat.votes == max {
-			mostVoted = append(mostVoted, playerStat)
-		} else if playerStat.votes == min {
-			leastVoted = append(leastVoted, playerStat)
-		} else {
-			neutral = append(neutral, playerStat)
+	default:
+		revealed.Votes = make(models.VoteMatrix, len(record.votes))
+		for voter, receivers := range record.votes {
+			revealed.Votes[voter] = append([]string{}, receivers...)
 		}
 	}
-	return leastVoted, neutral, mostVoted
+	return revealed
 }
 
-func givePoints(leastVoted, neutral, mostVoted []playerStat) models.QuestionPoints {
-	var qp models.QuestionPoints
-
-	calculatePoints := func(s []playerStat, sv SelfVote, pointToGiveOnCorrect int) {
-		for _, p := range s {
-			pe := models.PointsEntry{Player: p.name, SelfVote: string(p.selfVote), VotesReceived: p.votes}
-			qp = append(qp, pe)
-			if p.selfVote == sv {
-				qp[len(qp)-1].Points = pointToGiveOnCorrect
-
-			} else {
-				qp[len(qp)-1].Points = WrongVotedPoints
+// questionStats collects the stats of the question record for each of the players, g.mu must be held
+func (g *Game) questionStats(question int, record *questionRecord) QuestionStats {
+	stats := QuestionStats{Question: question}
+	for n := range g.players {
+		stats.Players = append(stats.Players, PlayerStat{
+			Name:       n,
+			Votes:      record.votesReceived(n),
+			SelfVote:   record.selfVotes[n],
+			Prediction: record.prediction(n),
+			Streak:     g.streak(n, question),
+		})
+	}
+	stats.Outcome = findOutcome(stats.Players)
+	return stats
+}
 
-			}
+// streak counts the questions in a row before the given question where the player guessed correctly
+func (g *Game) streak(playerName string, question int) int {
+	var streak int
+	for q := question - 1; q >= FirstQuestionNumber; q-- {
+		record, ok := g.ledger[q]
+		if !ok || !record.isCorrect(playerName) {
+			break
 		}
+		streak++
 	}
-	calculatePoints(leastVoted, LeastVoted, LeastVotedPoints)
-	calculatePoints(neutral, Neutral, NeutralPoints)
-	calculatePoints(mostVoted, MostVoted, MostVotedPoints)
-	return qp
+	return streak
 }
 
 // SetNextRoundFromPlayer sets the ready for next round flag to true
@@ -378,8 +651,8 @@ func (g *Game) SetPlayerReadyForNextRound(playerName string) error {
 
 // IsNextRound returns true if all player have set ready for next round flag
 func (g *Game) IsNextRound() bool {
-	allReady := true
 	g.mu.RLock()
+	allReady := len(g.players) > 0
 	for _, p := range g.players {
 		if p.readyForNextRound == false {
 			allReady = false
@@ -403,12 +676,36 @@ func (g *Game) resetAllReadyForNextRound() {
 	}
 }
 
-func (g *Game) AddCustomQuestion(question string) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	// we do not know the language, but we still want the Question type
-	g.customQuestions = append(g.customQuestions, models.Question{
-		Id:       "",
-		Question: models.QuestionTranslations{Norwegian: question, English: question},
-	})
+func (g *Game) Snapshot() models.GameSnapshot {
+	playersUpdate, _ := g.GetRoomStatus()
+	isStarted := g.IsStarted()
+	snapshot := models.GameSnapshot{
+		Event:           "game_snapshot",
+		Players:         playersUpdate,
+		IsStarted:       isStarted,
+		IsSelfVoting:    isStarted && g.IsSelfVoting(),
+		CurrentQuestion: g.currentQuestionNumber(),
+		Points:          g.CalculatePoints(FirstQuestionNumber, g.GetCurrentDoneQuestion()),
+		Seed:            g.Settings().Seed,
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if len(g.questions) >= QuestionsPerRound {
+		snapshot.Questions = g.questions[len(g.questions)-QuestionsPerRound:]
+	}
+	return snapshot
+}
+
+// IsStarted returns true once all players in the lobby have been ready to start the game
+func (g *Game) IsStarted() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.started
+}
+
+func (g *Game) currentQuestionNumber() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.currentQuestion
 }
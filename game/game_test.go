@@ -3,7 +3,9 @@ package game
 import (
 	"fmt"
 	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 )
 
@@ -14,10 +16,10 @@ const (
 	p1PointsPerRound, p2PointsPerRound, p3PointsPerRound = 9, 3, 9
 )
 
-const TestQuestionsPath = "../testQuestions.json"
+var TestQuestions = question.PackDir{Dir: "../testPacks"}
 
 func TestAddPlayer(t *testing.T) {
-	g := NewGame(TestQuestionsPath)
+	g := NewGame(TestQuestions, models.RoomSettings{})
 	players := []string{"Player AAA", "Player BBB"}
 	var ok bool
 	ok = g.AddPlayer(players[0])
@@ -95,9 +97,9 @@ func TestCalculatePointsFromLastRound(t *testing.T) {
 func TestFindMinAndMaxVotes(t *testing.T) {
 	const Max, Min = 2, 1
 	players := []string{"Player AAA", "Player BBB"}
-	ps := []playerStat{
-		{players[0], Max, "not_used_here"},
-		{players[1], Min, "not_used_here"},
+	ps := []PlayerStat{
+		{Name: players[0], Votes: Max, SelfVote: "not_used_here"},
+		{Name: players[1], Votes: Min, SelfVote: "not_used_here"},
 	}
 	min, max := findMinAndMaxVotes(ps)
 	if max != Max {
@@ -110,29 +112,29 @@ func TestFindMinAndMaxVotes(t *testing.T) {
 
 func TestGetVoteStats(t *testing.T) {
 	g := createTestableGame(t)
-	ps := getPlayerStats(g.players, 1)
+	ps := g.questionStats(1, g.ledger[1]).Players
 	for _, p := range ps {
-		switch p.name {
+		switch p.Name {
 		case p1:
-			if p.selfVote != MostVoted {
-				t.Errorf("expected self vote '%s', got '%s' ", MostVoted, p.selfVote)
+			if p.SelfVote != MostVoted {
+				t.Errorf("expected self vote '%s', got '%s' ", MostVoted, p.SelfVote)
 			}
-			if p.votes != p1Votes {
-				t.Errorf("expectes votes '%d', got '%d'", p1Votes, p.votes)
+			if p.Votes != p1Votes {
+				t.Errorf("expectes votes '%d', got '%d'", p1Votes, p.Votes)
 			}
 		case p2:
-			if p.selfVote != Neutral {
-				t.Errorf("expected self vote '%s', got '%s' ", Neutral, p.selfVote)
+			if p.SelfVote != Neutral {
+				t.Errorf("expected self vote '%s', got '%s' ", Neutral, p.SelfVote)
 			}
-			if p.votes != p2Votes {
-				t.Errorf("expectes votes '%d', got '%d'", p2Votes, p.votes)
+			if p.Votes != p2Votes {
+				t.Errorf("expectes votes '%d', got '%d'", p2Votes, p.Votes)
 			}
 		case p3:
-			if p.selfVote != LeastVoted {
-				t.Errorf("expected self vote '%s', got '%s' ", LeastVoted, p.selfVote)
+			if p.SelfVote != LeastVoted {
+				t.Errorf("expected self vote '%s', got '%s' ", LeastVoted, p.SelfVote)
 			}
-			if p.votes != p3Votes {
-				t.Errorf("expectes votes '%d', got '%d'", p3Votes, p.votes)
+			if p.Votes != p3Votes {
+				t.Errorf("expectes votes '%d', got '%d'", p3Votes, p.Votes)
 			}
 		default:
 			t.Errorf("unexpected name")
@@ -143,18 +145,18 @@ func TestGetVoteStats(t *testing.T) {
 
 func TestFindPlayerPositions(t *testing.T) {
 	g := createTestableGame(t)
-	lv, n, mv := findPlayerPositions(getPlayerStats(g.players, 1), p3Votes, p1Votes)
+	lv, n, mv := findPlayerPositions(g.questionStats(1, g.ledger[1]).Players, p3Votes, p1Votes)
 	if len(lv) != 1 || len(n) != 1 || len(mv) != 1 {
 		t.Errorf("expected all slices to be 1, got lv => '%d', n => '%d', mv => '%d'", len(lv), len(n), len(mv))
 	}
-	if lv[0].name != p3 || lv[0].selfVote != LeastVoted || lv[0].votes != p3Votes {
-		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p3, LeastVoted, p3Votes, lv[0].name, lv[0].votes, lv[0].selfVote)
+	if lv[0].Name != p3 || lv[0].SelfVote != LeastVoted || lv[0].Votes != p3Votes {
+		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p3, LeastVoted, p3Votes, lv[0].Name, lv[0].Votes, lv[0].SelfVote)
 	}
-	if n[0].name != p2 || n[0].selfVote != Neutral || n[0].votes != p2Votes {
-		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p2, Neutral, p2Votes, n[0].name, n[0].votes, n[0].selfVote)
+	if n[0].Name != p2 || n[0].SelfVote != Neutral || n[0].Votes != p2Votes {
+		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p2, Neutral, p2Votes, n[0].Name, n[0].Votes, n[0].SelfVote)
 	}
-	if mv[0].name != p1 || mv[0].selfVote != MostVoted || mv[0].votes != p1Votes {
-		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p1, MostVoted, p1Votes, mv[0].name, mv[0].votes, mv[0].selfVote)
+	if mv[0].Name != p1 || mv[0].SelfVote != MostVoted || mv[0].Votes != p1Votes {
+		t.Errorf("expected name '%s' to be '%s' and have '%d' votes, got name '%s', '%d' points and '%s'", p1, MostVoted, p1Votes, mv[0].Name, mv[0].Votes, mv[0].SelfVote)
 	}
 }
 
@@ -162,14 +164,14 @@ func TestGivePoints(t *testing.T) {
 	const p4 = "Player DDD"
 	const p4Votes = 2
 	g := createTestableGame(t)
-	lv, n, mv := findPlayerPositions(getPlayerStats(g.players, 1), p3Votes, p1Votes)
+	lv, n, mv := findPlayerPositions(g.questionStats(1, g.ledger[1]).Players, p3Votes, p1Votes)
 	// adding player who should receive zero points
-	n = append(n, playerStat{
-		name:     p4,
-		votes:    p4Votes,
-		selfVote: MostVoted,
+	n = append(n, PlayerStat{
+		Name:     p4,
+		Votes:    p4Votes,
+		SelfVote: MostVoted,
 	})
-	qp := givePoints(lv, n, mv)
+	qp := givePoints(lv, n, mv, nil)
 	for _, point := range qp {
 		switch point.Player {
 		case p1:
@@ -280,7 +282,7 @@ func TestGame_GetQuestions(t *testing.T) {
 
 	assertQuestions := func(startQuestionNum int) {
 		for i, qNum := 0, startQuestionNum; i < 4; i++ {
-			assert.Contains(t, expectedQuestions, questions[i].Question.English)
+			assert.Contains(t, expectedQuestions, questions[i].Question["en"])
 			qNum++
 		}
 	}
@@ -297,8 +299,8 @@ func TestGame_GetQuestions(t *testing.T) {
 
 	createFinishedGame(g, t)
 	questions, err = g.GetQuestions()
-	assert.Nil(t, questions, "should be no more questions in the testQuestions.json")
-	assert.EqualError(t, err, "no more questions: unable to find 4 questions, found 0")
+	assert.Nil(t, questions, "should be no more questions in the test pack")
+	assert.ErrorIs(t, err, ErrQuestionsExhausted)
 
 }
 
@@ -335,13 +337,18 @@ func TestGame_AddCustomQuestion(t *testing.T) {
 	expectedQuestions := []string{"q1", "q2", "q3", "q4"}
 	g := createTestableGame(t)
 	for _, q := range expectedQuestions {
-		g.AddCustomQuestion(q)
+		g.AddCustomQuestion(p1, q, "")
 	}
 
 	assert.Len(t, g.customQuestions, len(expectedQuestions))
+	ids := make(map[string]bool)
 	for i, q := range g.customQuestions {
-		assert.Equal(t, expectedQuestions[i], q.Question.English)
-		assert.Equal(t, expectedQuestions[i], q.Question.Norwegian)
+		assert.Equal(t, expectedQuestions[i], q.Question["en"], "the question is written in the default language")
+		assert.Len(t, q.Question, 1)
+		assert.Equal(t, p1, q.Author)
+		assert.NotEmpty(t, q.Id)
+		assert.False(t, ids[q.Id], "every custom question has its own id")
+		ids[q.Id] = true
 	}
 }
 
@@ -349,11 +356,11 @@ func TestGame_getCustomQuestions(t *testing.T) {
 	expectedQuestions := []string{"q1", "q2", "q3", "q4"}
 	g := createTestableGame(t)
 	for _, q := range expectedQuestions {
-		g.AddCustomQuestion(q)
+		g.AddCustomQuestion(p1, q, "")
 	}
 
 	for i, question := range g.getCustomQuestions() {
-		assert.Equal(t, expectedQuestions[i], question.Question.English)
+		assert.Equal(t, expectedQuestions[i], question.Question["en"])
 	}
 
 	assert.Len(t, g.customQuestions, 0)
@@ -362,23 +369,37 @@ func TestGame_getCustomQuestions(t *testing.T) {
 func TestGame_loadQuestions(t *testing.T) {
 	expectedQuestions := []string{"q1", "q2"}
 	g := createTestableGame(t)
-	g.AddCustomQuestion(expectedQuestions[0])
-	g.AddCustomQuestion(expectedQuestions[1])
+	g.AddCustomQuestion(p1, expectedQuestions[0], "")
+	g.AddCustomQuestion(p2, expectedQuestions[1], "")
 
 	err := g.loadQuestions()
 
 	assert.NoError(t, err)
 	assert.Len(t, g.customQuestions, 0)
 	assert.Len(t, g.questions, 4)
-	assert.Equal(t, expectedQuestions[0], g.questions[0].Question.English)
-	assert.Equal(t, expectedQuestions[1], g.questions[1].Question.English)
+	assert.Equal(t, expectedQuestions[0], g.questions[0].Question["en"])
+	assert.Equal(t, expectedQuestions[1], g.questions[1].Question["en"])
 	assert.NotEmpty(t, g.questions[2])
 	assert.NotEmpty(t, g.questions[3])
 }
 
+func TestGamesWithTheSameSeedGetTheSameQuestions(t *testing.T) {
+	play := func(seed int64) []string {
+		g := NewGame(TestQuestions, models.RoomSettings{Seed: seed})
+		g.AddPlayer(p1)
+		g.AddPlayer(p2)
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, g.loadQuestions())
+		}
+		return getQuestionIds(g.questions)
+	}
+	assert.Equal(t, play(42), play(42))
+	assert.NotEqual(t, play(42), play(43))
+}
+
 // createTestableGame creates a game with one question done
 func createTestableGame(t *testing.T) *Game {
-	g := NewGame(TestQuestionsPath)
+	g := NewGame(TestQuestions, models.RoomSettings{})
 	g.AddPlayer(p1)
 	g.AddPlayer(p2)
 	g.AddPlayer(p3)
@@ -415,3 +436,227 @@ func createSelfVote(playerName string, selfVote SelfVote) models.RegisterSelfVot
 		},
 	}
 }
+
+func TestIsPlayersReadyRequiresMinPlayers(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{MinPlayers: 3})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+	_ = g.SetPlayerReadyToStartGame(p1)
+	_ = g.SetPlayerReadyToStartGame(p2)
+
+	ready, readyPlayers := g.IsPlayersReady()
+	assert.False(t, ready, "game should not start with less than the minimum players")
+	assert.Len(t, readyPlayers, 2)
+	_, isAllReady := g.GetRoomStatus()
+	assert.False(t, isAllReady)
+
+	g.AddPlayer(p3)
+	_ = g.SetPlayerReadyToStartGame(p3)
+	ready, _ = g.IsPlayersReady()
+	assert.True(t, ready)
+	_, isAllReady = g.GetRoomStatus()
+	assert.True(t, isAllReady)
+}
+
+func TestAddPlayerMaxPlayers(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{MinPlayers: 2, MaxPlayers: 2})
+	assert.True(t, g.AddPlayer(p1))
+	assert.True(t, g.AddPlayer(p2))
+	assert.False(t, g.AddPlayer(p3), "game is full")
+}
+
+func TestGame_Snapshot(t *testing.T) {
+	g := createTestableGame(t)
+	for _, p := range []string{p1, p2, p3} {
+		_ = g.SetPlayerReadyToStartGame(p)
+	}
+	_, err := g.GetQuestions()
+	assert.NoError(t, err)
+
+	s := g.Snapshot()
+	assert.Equal(t, "game_snapshot", s.Event)
+	assert.True(t, s.IsStarted)
+	assert.Len(t, s.Players, NumberOfPlayers)
+	assert.Len(t, s.Questions, QuestionsPerRound)
+	assert.Equal(t, 2, s.CurrentQuestion)
+	assert.Len(t, s.Points, NumberOfPlayers)
+	assert.NotZero(t, s.Seed, "a random seed is chosen when the room did not set one")
+	assert.Equal(t, g.Settings().Seed, s.Seed)
+}
+
+func TestLateJoinerIsQueuedUntilNextRound(t *testing.T) {
+	const p4 = "Player DDD"
+	g := createTestableGame(t)
+	for _, p := range []string{p1, p2, p3} {
+		_ = g.SetPlayerReadyToStartGame(p)
+	}
+	assert.True(t, g.IsStarted())
+
+	assert.True(t, g.AddPlayer(p4))
+	assert.True(t, g.IsQueued(p4))
+	assert.NotContains(t, g.players, p4)
+
+	createFinishedGame(g, t)
+	assert.Equal(t, []string{p4}, g.AdmitQueuedPlayers())
+	assert.Contains(t, g.players, p4)
+	assert.False(t, g.IsQueued(p4))
+
+	// the late joiner should not change the points of the questions played before joining
+	// createFinishedGame plays the three last questions of the round
+	p := g.CalculatePoints(FirstQuestionNumber+1, g.GetCurrentDoneQuestion())
+	expectedPoints := expectedPointsAfterRound(1)
+	expectedPoints[p4] = 0
+	assert.Len(t, p, NumberOfPlayers+1)
+	for _, entry := range p {
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+	}
+
+	ready, _ := g.IsPlayersReady()
+	assert.True(t, ready, "admitted players does not need to ready up in the lobby")
+}
+
+func TestGameStartsWhenTheLastUnreadyPlayerLeaves(t *testing.T) {
+	const p4 = "Player DDD"
+	g := NewGame(TestQuestions, models.RoomSettings{MinPlayers: 2})
+	for _, p := range []string{p1, p2, p3} {
+		g.AddPlayer(p)
+	}
+	_ = g.SetPlayerReadyToStartGame(p1)
+	_ = g.SetPlayerReadyToStartGame(p2)
+	assert.False(t, g.IsStarted())
+
+	g.RemovePlayer(p3)
+	assert.True(t, g.IsStarted(), "everyone left in the lobby is ready")
+	assert.True(t, g.AddPlayer(p4))
+	assert.True(t, g.IsQueued(p4), "players joining after the start wait for the next round")
+}
+
+func TestIsSelfVotingDoesNotChangeTheLedger(t *testing.T) {
+	g := createTestableGame(t)
+	g.currentQuestion++
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.False(t, g.IsSelfVoting())
+		}()
+		go func() {
+			defer wg.Done()
+			g.Snapshot()
+		}()
+	}
+	wg.Wait()
+	assert.NotContains(t, g.ledger, g.currentQuestion, "nobody has voted on the question")
+}
+
+func TestRemovePlayerDuringQuestionVoting(t *testing.T) {
+	g := createTestableGame(t)
+	g.SetVotesFromPlayer(createTwoVotes(p1, p3))
+	g.SetVotesFromPlayer(createTwoVotes(p2, p1))
+	assert.False(t, g.IsSelfVoting())
+
+	d := g.RemovePlayer(p3)
+	assert.Equal(t, 2, d.Question)
+	assert.Equal(t, 0, d.VotesWithdrawn)
+	assert.Equal(t, 2, d.VotesDiscarded)
+	assert.True(t, d.SelfVotingStarted, "the remaining players have voted")
+	assert.True(t, g.IsSelfVoting())
+	assert.Equal(t, 2, g.ledger[2].votesReceived(p1))
+	assert.Empty(t, g.ledger[2].votes[p1])
+
+	g.SetSelfVoteFromPlayer(createSelfVote(p1, MostVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p2, LeastVoted))
+	questionDone, _ := g.IsRoundFinished()
+	assert.True(t, questionDone)
+}
+
+func TestRemovePlayerWithdrawsVotes(t *testing.T) {
+	g := createTestableGame(t)
+	g.SetVotesFromPlayer(createTwoVotes(p1, p2))
+	g.SetVotesFromPlayer(createTwoVotes(p2, p1))
+	g.SetVotesFromPlayer(createTwoVotes(p3, p1))
+	g.SetSelfVoteFromPlayer(createSelfVote(p1, MostVoted))
+
+	d := g.RemovePlayer(p3)
+	assert.Equal(t, 2, d.VotesWithdrawn)
+	assert.Equal(t, 0, d.VotesDiscarded)
+	assert.False(t, d.SelfVotingStarted, "self voting had already started")
+	assert.Equal(t, 2, g.ledger[2].votesReceived(p1))
+
+	g.SetSelfVoteFromPlayer(createSelfVote(p2, LeastVoted))
+	questionDone, _ := g.IsRoundFinished()
+	assert.True(t, questionDone)
+}
+
+func TestPointsOfDoneQuestionsAreKeptWhenPlayerLeaves(t *testing.T) {
+	g := createTestableGame(t)
+	before := g.CalculatePointsForCurrentQuestion()
+
+	g.RemovePlayer(p1)
+
+	assert.Equal(t, before, g.CalculatePointsForCurrentQuestion(), "done questions should not be scored again")
+	expectedPoints := map[string]int{p2: NeutralPoints, p3: LeastVotedPoints}
+	points := g.CalculatePoints(FirstQuestionNumber, g.GetCurrentDoneQuestion())
+	assert.Len(t, points, len(expectedPoints), "players who left are not in the standings")
+	for _, entry := range points {
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+	}
+}
+
+func TestSetSelfVoteFromPlayerValidatesPrediction(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{Scoring: models.PredictionScoring})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+
+	tooMany, valid := g.MaxPrediction()+1, 2
+	assert.Error(t, g.SetSelfVoteFromPlayer(createSelfVote(p1, MostVoted)), "a prediction is required")
+	assert.Error(t, g.SetSelfVoteFromPlayer(models.RegisterSelfVote{Player: p1, Prediction: &tooMany}))
+	assert.NoError(t, g.SetSelfVoteFromPlayer(models.RegisterSelfVote{Player: p1, Prediction: &valid}))
+	assert.True(t, g.currentRecord().hasSelfVoted(p1))
+	assert.Error(t, g.SetSelfVoteFromPlayer(models.RegisterSelfVote{Player: "unknown", Prediction: &valid}))
+}
+
+func TestSetSelfVoteFromPlayerValidatesChoice(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+
+	assert.Error(t, g.SetSelfVoteFromPlayer(createSelfVote(p1, "SOMETHING")))
+	assert.NoError(t, g.SetSelfVoteFromPlayer(createSelfVote(p1, Neutral)))
+}
+
+func TestGetVotesForCurrentQuestion(t *testing.T) {
+	g := createTestableGame(t)
+	assert.Nil(t, g.GetVotesForCurrentQuestion(), "votes are anonymous by default")
+
+	g.settings.Voting = models.RevealedVoting
+	expected := models.VoteMatrix{p1: {p2, p2}, p2: {p1, p1}, p3: {p1, p1}}
+	assert.Equal(t, &models.RevealedVotes{Votes: expected}, g.GetVotesForCurrentQuestion())
+}
+
+func TestHostIsPassedOnWhenTheHostLeaves(t *testing.T) {
+	g := createTestableGame(t)
+	assert.Equal(t, p1, g.Host())
+
+	g.RemovePlayer(p1)
+	assert.Equal(t, p2, g.Host())
+	players, _ := g.GetRoomStatus()
+	for _, p := range players {
+		assert.Equal(t, p.Name == p2, p.IsHost, p.Name)
+	}
+}
+
+func TestSelectPacks(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+
+	assert.ErrorIs(t, g.SelectPacks(p2, []string{"test"}), ErrNotHost)
+	assert.Error(t, g.SelectPacks(p1, []string{"unknown"}))
+	assert.NoError(t, g.SelectPacks(p1, []string{"test"}))
+	assert.Equal(t, []string{"test"}, g.Settings().Packs)
+
+	packs, err := g.Packs()
+	assert.NoError(t, err)
+	assert.Len(t, packs, 1)
+}
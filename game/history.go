@@ -0,0 +1,57 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"log"
+	"sort"
+	"strings"
+)
+
+// SetName sets the name of the room the game is played in, games in rooms with the same name are one group with RoomHistory
+func (g *Game) SetName(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.name = name
+}
+
+// historyGroup returns the key of the group playing the game, it is empty when the game does not use a history.
+// g.mu must be held.
+func (g *Game) historyGroup() string {
+	switch g.settings.History {
+	case models.RoomHistory:
+		return "room:" + strings.ToLower(g.name)
+	case models.PlayersHistory:
+		var names []string
+		for name := range g.players {
+			names = append(names, strings.ToLower(name))
+		}
+		sort.Strings(names)
+		return "players:" + strings.Join(names, ",")
+	default:
+		return ""
+	}
+}
+
+// useHistory makes the store serve the questions the group has not played recently first, g.mu must be held
+func (g *Game) useHistory() {
+	grouper, ok := g.questionStore.(question.Grouper)
+	group := g.historyGroup()
+	if !ok || group == "" {
+		return
+	}
+	if err := grouper.UseGroup(group); err != nil {
+		log.Printf("unable to use the history of '%s': %s", group, err)
+	}
+}
+
+// recordPlayed remembers the questions from the store which are played, g.mu must be held
+func (g *Game) recordPlayed(questions []models.Question) {
+	grouper, ok := g.questionStore.(question.Grouper)
+	if !ok || len(questions) == 0 {
+		return
+	}
+	if err := grouper.Played(getQuestionIds(questions)); err != nil {
+		log.Printf("unable to record the played questions: %s", err)
+	}
+}
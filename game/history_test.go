@@ -0,0 +1,64 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHistoryGroup(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{History: models.PlayersHistory})
+	g.AddPlayer(p2)
+	g.AddPlayer(p1)
+	other := NewGame(TestQuestions, models.RoomSettings{History: models.PlayersHistory})
+	other.AddPlayer("player aaa")
+	other.AddPlayer("player bbb")
+	assert.Equal(t, g.historyGroup(), other.historyGroup(), "the same players are the same group regardless of order and case")
+
+	g = NewGame(TestQuestions, models.RoomSettings{History: models.RoomHistory})
+	g.SetName("Friday")
+	assert.Equal(t, "room:friday", g.historyGroup())
+
+	g = NewGame(TestQuestions, models.RoomSettings{})
+	assert.Empty(t, g.historyGroup())
+}
+
+func TestOnlyPlayedQuestionsAreRecorded(t *testing.T) {
+	history := question.NewMemoryHistory()
+	source, err := question.NewCatalog("../testPacks", history)
+	assert.NoError(t, err)
+	g := NewGame(source, models.RoomSettings{History: models.RoomHistory})
+	g.SetName("Friday")
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+	g.AddPlayer(p3)
+	for _, name := range []string{p1, p2, p3} {
+		assert.NoError(t, g.SetPlayerReadyToStartGame(name))
+	}
+	g.AddCustomQuestion(p1, "custom", "")
+
+	assert.NoError(t, g.loadQuestions())
+	seen, _ := history.Seen("room:friday")
+	assert.Len(t, seen, QuestionsPerRound-1, "the custom question and the question it replaced are not recorded")
+	for _, q := range g.questions[1:] {
+		assert.Contains(t, seen, q.Id)
+	}
+}
+
+func TestHistoryIsUsedWhenALeavingPlayerStartsTheGame(t *testing.T) {
+	history := question.NewMemoryHistory()
+	source, err := question.NewCatalog("../testPacks", history)
+	assert.NoError(t, err)
+	g := NewGame(source, models.RoomSettings{MinPlayers: 2, History: models.PlayersHistory})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+	g.AddPlayer(p3)
+	assert.NoError(t, g.SetPlayerReadyToStartGame(p1))
+	assert.NoError(t, g.SetPlayerReadyToStartGame(p2))
+	g.RemovePlayer(p3)
+
+	assert.NoError(t, g.loadQuestions())
+	seen, _ := history.Seen("players:player aaa,player bbb")
+	assert.Len(t, seen, QuestionsPerRound, "the group is the players who started the game")
+}
@@ -0,0 +1,144 @@
+package game
+
+import "github.com/akselleirv/introspect/models"
+
+// questionRecord is the ledger entry for one question,
+// phase checks, scoring and history are all read from it
+type questionRecord struct {
+	// question is the question the votes were cast on, the type of the question decides how the votes are counted
+	question models.Question
+	// votes maps the voter to the players who received the votes, or the players ranked in a ranking question.
+	// Every player who has voted is in votes.
+	votes map[string][]string
+	// ratings maps the voter to the rating of each player in a rating question
+	ratings map[string]map[string]int
+	// options maps the voter to the option chosen in a this or that question
+	options   map[string]int
+	selfVotes map[string]SelfVote
+	// predictions are the number of votes the players predicts to receive, used instead of selfVotes
+	// when the game uses PredictionScoring
+	predictions map[string]int
+	// points are awarded when all players have self voted, and are not changed afterwards
+	points  models.QuestionPoints
+	outcome models.ScoringOutcome
+	done    bool
+	// teams are the teams of the players when the question was scored, and teamBonus the bonus given to the teams
+	teams     map[string]string
+	teamBonus map[string]int
+}
+
+func newQuestionRecord() *questionRecord {
+	return &questionRecord{
+		votes:       make(map[string][]string),
+		ratings:     make(map[string]map[string]int),
+		options:     make(map[string]int),
+		selfVotes:   make(map[string]SelfVote),
+		predictions: make(map[string]int),
+	}
+}
+
+func (q *questionRecord) hasVoted(playerName string) bool {
+	_, ok := q.votes[playerName]
+	return ok
+}
+
+func (q *questionRecord) hasSelfVoted(playerName string) bool {
+	_, hasPredicted := q.predictions[playerName]
+	return q.selfVotes[playerName] != "" || hasPredicted
+}
+
+// prediction returns the prediction of the player, or nil if the player has not predicted
+func (q *questionRecord) prediction(playerName string) *int {
+	if p, ok := q.predictions[playerName]; ok {
+		return &p
+	}
+	return nil
+}
+
+// isCorrect returns true if the question is done and the player guessed correctly
+func (q *questionRecord) isCorrect(playerName string) bool {
+	if !q.done {
+		return false
+	}
+	for _, entry := range q.points {
+		if entry.Player == playerName {
+			return entry.Correct
+		}
+	}
+	return false
+}
+
+// votesReceived counts the votes given to the player by all voters, the type of the question decides what is a vote
+func (q *questionRecord) votesReceived(playerName string) int {
+	var received int
+	switch questionType(q.question) {
+	case models.RankingQuestion:
+		for _, ranking := range q.votes {
+			for i, name := range ranking {
+				if name == playerName {
+					received += len(ranking) - 1 - i
+				}
+			}
+		}
+	case models.RatingQuestion:
+		for _, ratings := range q.ratings {
+			received += ratings[playerName]
+		}
+	case models.ThisOrThatQuestion:
+		option, hasChosen := q.options[playerName]
+		if !hasChosen {
+			return 0
+		}
+		for _, o := range q.options {
+			if o == option {
+				received++
+			}
+		}
+	default:
+		for _, receivers := range q.votes {
+			for _, receiver := range receivers {
+				if receiver == playerName {
+					received++
+				}
+			}
+		}
+	}
+	return received
+}
+
+// withdrawVotesFrom removes the votes cast by the player, and returns the number of votes removed
+func (q *questionRecord) withdrawVotesFrom(playerName string) int {
+	withdrawn := len(q.votes[playerName])
+	for _, rating := range q.ratings[playerName] {
+		withdrawn += rating
+	}
+	if _, hasChosen := q.options[playerName]; hasChosen {
+		withdrawn++
+	}
+	delete(q.votes, playerName)
+	delete(q.ratings, playerName)
+	delete(q.options, playerName)
+	return withdrawn
+}
+
+// discardVotesFor removes the votes cast for the player, and returns the number of votes removed.
+// The voters are still counted as having voted.
+func (q *questionRecord) discardVotesFor(playerName string) int {
+	var discarded int
+	for voter, receivers := range q.votes {
+		kept := []string{}
+		for _, receiver := range receivers {
+			if receiver == playerName {
+				discarded++
+			} else {
+				kept = append(kept, receiver)
+			}
+		}
+		q.votes[voter] = kept
+	}
+	for _, ratings := range q.ratings {
+		discarded += ratings[playerName]
+		delete(ratings, playerName)
+	}
+	return discarded
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"log"
+	"sort"
+)
+
+var ErrNotHost = errors.New("only the host can change the room")
+
+// ValidatePacks returns an error if one of the packs in the settings is not in the source
+func ValidatePacks(source question.Source, s models.RoomSettings) error {
+	if err := question.ValidatePacks(source, s.Packs); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSettings, err)
+	}
+	return nil
+}
+
+// newQuestionStore creates the store with the packs in the settings, all packs are used if the packs can not be loaded
+func newQuestionStore(source question.Source, settings models.RoomSettings) question.Questioner {
+	filter := question.NewFilter(settings)
+	store, err := source.NewStore(settings.Packs, filter, settings.Seed)
+	if err == nil {
+		return store
+	}
+	log.Printf("unable to use the packs %v, using all packs instead: %s", settings.Packs, err)
+	if store, err = source.NewStore(nil, filter, settings.Seed); err != nil {
+		log.Printf("unable to load the question packs: %s", err)
+		return &question.Store{}
+	}
+	return store
+}
+
+// Host returns the name of the player who can change the room in the lobby
+func (g *Game) Host() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.host
+}
+
+// nextHost returns the player who becomes host when the host leaves, g.mu must be held
+func (g *Game) nextHost() string {
+	var names []string
+	for name := range g.players {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// SelectPacks lets the host choose the question packs before the game has started, all packs are played when it is empty
+func (g *Game) SelectPacks(playerName string, packs []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if playerName != g.host {
+		return ErrNotHost
+	}
+	if g.started {
+		return errors.New("the packs can not be changed after the game has started")
+	}
+	store, err := g.questionSource.NewStore(packs, question.NewFilter(g.settings), g.settings.Seed)
+	if err != nil {
+		return err
+	}
+	g.questionStore = store
+	g.settings.Packs = packs
+	return nil
+}
+
+// Packs returns the manifests of all the packs the host can choose from
+func (g *Game) Packs() ([]models.PackManifest, error) {
+	return g.questionSource.Packs()
+}
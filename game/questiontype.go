@@ -0,0 +1,151 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+)
+
+// DefaultRatingScale is used by rating questions without a scale
+const DefaultRatingScale = 5
+
+// questionType returns the type of the question, questions without a type are most likely questions
+func questionType(q models.Question) models.QuestionType {
+	if q.Type == "" {
+		return models.MostLikelyQuestion
+	}
+	return q.Type
+}
+
+func ratingScale(q models.Question) int {
+	if q.Scale == 0 {
+		return DefaultRatingScale
+	}
+	return q.Scale
+}
+
+// votesPerPlayer returns the number of votes each player gives in a most likely question
+func votesPerPlayer(players int) int {
+	votes := (players + PlayersPerVote - 1) / PlayersPerVote
+	if votes < MinVotesPerQuestion {
+		return MinVotesPerQuestion
+	}
+	if votes > MaxVotesPerQuestion {
+		return MaxVotesPerQuestion
+	}
+	return votes
+}
+
+// questionVotes returns the votes each player gives in the most likely question,
+// it is computed from the players when the question does not decide it
+func questionVotes(q models.Question, players int) int {
+	if q.VotesPerPlayer > 0 {
+		return q.VotesPerPlayer
+	}
+	return votesPerPlayer(players)
+}
+
+// maxVotesReceived returns the most votes a player can receive when every player votes for the player
+func maxVotesReceived(q models.Question, players int) int {
+	switch questionType(q) {
+	case models.RankingQuestion:
+		return (players - 1) * players
+	case models.RatingQuestion:
+		return ratingScale(q) * players
+	case models.ThisOrThatQuestion:
+		return players
+	default:
+		return questionVotes(q, players) * players
+	}
+}
+
+// question returns the question with the given number, or an empty question if it is not loaded, g.mu must be held
+func (g *Game) question(number int) models.Question {
+	if number < FirstQuestionNumber || number > len(g.questions) {
+		return models.Question{}
+	}
+	return g.questions[number-1]
+}
+
+// castBallot validates the votes for the type of the question, and stores them in the record. g.mu must be held.
+func (g *Game) castBallot(record *questionRecord, votes models.PlayerVotedOnQuestion) error {
+	switch questionType(record.question) {
+	case models.RankingQuestion:
+		return g.castRanking(record, votes)
+	case models.RatingQuestion:
+		return g.castRatings(record, votes)
+	case models.ThisOrThatQuestion:
+		return castOption(record, votes)
+	case models.MostLikelyQuestion:
+		return g.castMostLikely(record, votes)
+	default:
+		return fmt.Errorf("unknown question type '%s'", record.question.Type)
+	}
+}
+
+// castMostLikely keeps the votes for players in the game, a player can not give more votes than the question allows
+func (g *Game) castMostLikely(record *questionRecord, votes models.PlayerVotedOnQuestion) error {
+	allowed := questionVotes(record.question, len(g.players))
+	if len(votes.Votes) != allowed {
+		return fmt.Errorf("exactly %d votes are required, got %d", allowed, len(votes.Votes))
+	}
+	receivers := []string{}
+	for _, v := range votes.Votes {
+		if _, exist := g.players[v.PlayerWhoReceivedTheVote]; !exist {
+			return fmt.Errorf("player '%s' is not in the game", v.PlayerWhoReceivedTheVote)
+		}
+		receivers = append(receivers, v.PlayerWhoReceivedTheVote)
+	}
+	record.votes[votes.Player] = receivers
+	return nil
+}
+
+// castRanking requires every player in the game to be ranked once
+func (g *Game) castRanking(record *questionRecord, votes models.PlayerVotedOnQuestion) error {
+	if len(votes.Ranking) != len(g.players) {
+		return fmt.Errorf("all %d players must be ranked, got %d", len(g.players), len(votes.Ranking))
+	}
+	ranked := make(map[string]bool)
+	for _, name := range votes.Ranking {
+		if _, exist := g.players[name]; !exist || ranked[name] {
+			return fmt.Errorf("player '%s' is unknown or ranked more than once", name)
+		}
+		ranked[name] = true
+	}
+	record.votes[votes.Player] = append([]string{}, votes.Ranking...)
+	return nil
+}
+
+// castRatings requires every player in the game to be rated within the scale of the question
+func (g *Game) castRatings(record *questionRecord, votes models.PlayerVotedOnQuestion) error {
+	if len(votes.Ratings) != len(g.players) {
+		return fmt.Errorf("all %d players must be rated, got %d", len(g.players), len(votes.Ratings))
+	}
+	scale := ratingScale(record.question)
+	ratings := make(map[string]int)
+	for name, rating := range votes.Ratings {
+		if _, exist := g.players[name]; !exist {
+			return fmt.Errorf("unable to rate unknown player '%s'", name)
+		}
+		if rating < 1 || rating > scale {
+			return fmt.Errorf("rating must be between 1 and %d, got %d", scale, rating)
+		}
+		ratings[name] = rating
+	}
+	record.votes[votes.Player] = []string{}
+	record.ratings[votes.Player] = ratings
+	return nil
+}
+
+// castOption requires one of the options of the question to be chosen
+func castOption(record *questionRecord, votes models.PlayerVotedOnQuestion) error {
+	if votes.Option == nil {
+		return errors.New("an option must be chosen")
+	}
+	if *votes.Option < 0 || *votes.Option >= len(record.question.Options) {
+		return fmt.Errorf("unknown option %d", *votes.Option)
+	}
+	record.votes[votes.Player] = []string{}
+	record.options[votes.Player] = *votes.Option
+	return nil
+}
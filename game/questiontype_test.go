@@ -0,0 +1,119 @@
+package game
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// createGameWithQuestion creates a game with three players where the first question is the given question
+func createGameWithQuestion(q models.Question) *Game {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+	g.AddPlayer(p2)
+	g.AddPlayer(p3)
+	g.questions = []models.Question{q}
+	return &g
+}
+
+func TestRankingQuestion(t *testing.T) {
+	g := createGameWithQuestion(models.Question{Type: models.RankingQuestion})
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ranking: []string{p1, p2}}), "all players must be ranked")
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ranking: []string{p1, p1, p2}}))
+
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ranking: []string{p1, p2, p3}}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p2, Ranking: []string{p1, p3, p2}}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p3, Ranking: []string{p2, p1, p3}}))
+	assert.True(t, g.IsSelfVoting())
+
+	record := g.currentRecord()
+	assert.Equal(t, 5, record.votesReceived(p1))
+	assert.Equal(t, 3, record.votesReceived(p2))
+	assert.Equal(t, 1, record.votesReceived(p3))
+	assert.Equal(t, 6, g.MaxPrediction())
+}
+
+func TestRatingQuestion(t *testing.T) {
+	g := createGameWithQuestion(models.Question{Type: models.RatingQuestion, Scale: 3})
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ratings: map[string]int{p1: 1, p2: 2, p3: 4}}), "the rating is above the scale")
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ratings: map[string]int{p1: 1}}), "all players must be rated")
+
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Ratings: map[string]int{p1: 1, p2: 2, p3: 3}}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p2, Ratings: map[string]int{p1: 1, p2: 1, p3: 3}}))
+
+	record := g.currentRecord()
+	assert.Equal(t, 2, record.votesReceived(p1))
+	assert.Equal(t, 6, record.votesReceived(p3))
+
+	d := g.RemovePlayer(p3)
+	assert.Equal(t, 6, d.VotesDiscarded)
+	assert.Equal(t, 0, record.votesReceived(p3))
+	assert.True(t, g.IsSelfVoting())
+}
+
+func TestThisOrThatQuestion(t *testing.T) {
+	options := []models.QuestionTranslations{{"en": "Cats"}, {"en": "Dogs"}}
+	g := createGameWithQuestion(models.Question{Type: models.ThisOrThatQuestion, Options: options})
+	cats, dogs, unknown := 0, 1, 2
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1}), "an option must be chosen")
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Option: &unknown}))
+
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Option: &cats}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p2, Option: &cats}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p3, Option: &dogs}))
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p3, Option: &cats}), "a player can only vote once")
+
+	g.SetSelfVoteFromPlayer(createSelfVote(p1, MostVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p2, LeastVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p3, LeastVoted))
+	questionDone, _ := g.IsRoundFinished()
+	assert.True(t, questionDone)
+
+	expectedPoints := map[string]int{p1: MostVotedPoints, p2: WrongVotedPoints, p3: LeastVotedPoints}
+	for _, entry := range g.CalculatePointsForCurrentQuestion() {
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+	}
+
+	g.settings.Voting = models.RevealedVoting
+	assert.Equal(t, &models.RevealedVotes{Options: map[string]int{p1: cats, p2: cats, p3: dogs}}, g.GetVotesForCurrentQuestion())
+}
+
+func TestVotesPerPlayer(t *testing.T) {
+	var tests = []struct {
+		players  int
+		expected int
+	}{
+		{2, MinVotesPerQuestion},
+		{3, 2},
+		{7, 3},
+		{12, 4},
+		{15, 5},
+		{HighestMaxPlayers, MaxVotesPerQuestion},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			assert.Equal(t, tt.expected, votesPerPlayer(tt.players))
+		})
+	}
+}
+
+func TestMostLikelyQuestionLimitsVotes(t *testing.T) {
+	g := createGameWithQuestion(models.Question{Type: models.MostLikelyQuestion, VotesPerPlayer: 1})
+	assert.Error(t, g.SetVotesFromPlayer(createTwoVotes(p1, p2)), "the question only allows one vote")
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1}), "the vote must be cast")
+	assert.Error(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Votes: []models.Vote{{PlayerWhoReceivedTheVote: "unknown"}}}))
+	assert.NoError(t, g.SetVotesFromPlayer(models.PlayerVotedOnQuestion{Player: p1, Votes: []models.Vote{{PlayerWhoReceivedTheVote: p2}}}))
+	assert.Equal(t, 1, g.currentRecord().votesReceived(p2))
+	assert.Equal(t, 3, g.MaxPrediction())
+}
+
+func TestGetQuestionsSetsVotesPerPlayer(t *testing.T) {
+	g := createGameWithQuestion(models.Question{})
+	g.questions = nil
+	questions, err := g.GetQuestions()
+	assert.NoError(t, err)
+	for _, q := range questions {
+		assert.Equal(t, votesPerPlayer(NumberOfPlayers), q.VotesPerPlayer, q.Id)
+	}
+}
@@ -0,0 +1,244 @@
+package game
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"math"
+)
+
+const (
+	MostVotedPoints  = 3
+	NeutralPoints    = 1
+	LeastVotedPoints = 3
+	WrongVotedPoints = 0
+
+	// PredictionPoints are given for an exact prediction, and one point less for each vote the prediction is off by
+	PredictionPoints = 3
+
+	// StreakBonusPoints are given for each correct guess in a row before the question
+	StreakBonusPoints = 1
+	MaxStreakBonus    = 3
+)
+
+// Scorer gives the players points for a question
+type Scorer interface {
+	Score(stats QuestionStats) models.QuestionPoints
+}
+
+// QuestionStats are the stats for one question which is scored
+type QuestionStats struct {
+	Question int
+	Players  []PlayerStat
+	// Outcome tells if the votes separated the players or if they are tied
+	Outcome models.ScoringOutcome
+}
+
+type PlayerStat struct {
+	Name     string
+	Votes    int
+	SelfVote SelfVote
+	// Prediction is the number of votes the player predicted to receive, only used with PredictionScoring
+	Prediction *int
+	// Streak is the number of questions in a row before this question where the player guessed correctly
+	Streak int
+}
+
+// NewScorer returns the scorer chosen in the settings
+func NewScorer(settings models.RoomSettings) (Scorer, error) {
+	switch settings.Scoring {
+	case models.SelfAssessmentScoring:
+		return SelfAssessmentScorer{TieRule: settings.TieRule}, nil
+	case models.ProportionalScoring:
+		return ProportionalScorer{TieRule: settings.TieRule}, nil
+	case models.StreakScoring:
+		return StreakScorer{SelfAssessmentScorer{TieRule: settings.TieRule}}, nil
+	case models.PredictionScoring:
+		return PredictionScorer{}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown scoring '%s'", ErrInvalidSettings, settings.Scoring)
+	}
+}
+
+// SelfAssessmentScorer gives points to the players who guessed if they were most voted, neutral or least voted
+type SelfAssessmentScorer struct {
+	TieRule models.TieRule
+}
+
+func (s SelfAssessmentScorer) Score(stats QuestionStats) models.QuestionPoints {
+	leastVoted, neutral, mostVoted, unplaced := placePlayers(stats, s.TieRule)
+	return givePoints(leastVoted, neutral, mostVoted, unplaced)
+}
+
+// ProportionalScorer places the players like the SelfAssessmentScorer, but the points for a correct guess
+// depends on the share of the votes. The more votes compared to an even share, the more points for
+// guessing most voted, and the fewer votes, the more points for guessing least voted.
+type ProportionalScorer struct {
+	TieRule models.TieRule
+}
+
+func (s ProportionalScorer) Score(stats QuestionStats) models.QuestionPoints {
+	var totalVotes int
+	for _, p := range stats.Players {
+		totalVotes += p.Votes
+	}
+	evenShare := float64(totalVotes) / float64(len(stats.Players))
+
+	qp := s.selfAssessment().Score(stats)
+	for i, entry := range qp {
+		if !entry.Correct || evenShare == 0 {
+			continue
+		}
+		switch SelfVote(entry.Position) {
+		case MostVoted:
+			qp[i].Points = int(math.Round(MostVotedPoints * float64(entry.VotesReceived) / evenShare))
+		case LeastVoted:
+			qp[i].Points = int(math.Round(LeastVotedPoints * (evenShare - float64(entry.VotesReceived)) / evenShare))
+		}
+		// a correct guess always gives at least one point
+		if qp[i].Points < 1 {
+			qp[i].Points = 1
+		}
+	}
+	return qp
+}
+
+func (s ProportionalScorer) selfAssessment() SelfAssessmentScorer {
+	return SelfAssessmentScorer{TieRule: s.TieRule}
+}
+
+// StreakScorer gives the same points as the SelfAssessmentScorer,
+// and a bonus for a correct guess when the previous guesses in a row also were correct
+type StreakScorer struct {
+	SelfAssessmentScorer
+}
+
+func (s StreakScorer) Score(stats QuestionStats) models.QuestionPoints {
+	streaks := make(map[string]int)
+	for _, p := range stats.Players {
+		streaks[p.Name] = p.Streak
+	}
+
+	qp := s.SelfAssessmentScorer.Score(stats)
+	for i, entry := range qp {
+		if !entry.Correct {
+			continue
+		}
+		streak := streaks[entry.Player]
+		if streak > MaxStreakBonus {
+			streak = MaxStreakBonus
+		}
+		qp[i].Points += streak * StreakBonusPoints
+	}
+	return qp
+}
+
+// PredictionScorer gives points for predicting the number of votes received,
+// the points are reduced by how far off the prediction was
+type PredictionScorer struct{}
+
+func (s PredictionScorer) Score(stats QuestionStats) models.QuestionPoints {
+	var qp models.QuestionPoints
+	for _, p := range stats.Players {
+		pe := models.PointsEntry{Player: p.Name, VotesReceived: p.Votes, Prediction: p.Prediction, Points: WrongVotedPoints}
+		if p.Prediction != nil {
+			off := *p.Prediction - p.Votes
+			if off < 0 {
+				off = -off
+			}
+			pe.Correct = off == 0
+			if off < PredictionPoints {
+				pe.Points = PredictionPoints - off
+			}
+		}
+		qp = append(qp, pe)
+	}
+	return qp
+}
+
+// placePlayers finds the position of the players, the tie rule decides the positions when the votes do not
+// separate the players. Players who are unplaced can not get any points.
+func placePlayers(stats QuestionStats, rule models.TieRule) (leastVoted, neutral, mostVoted, unplaced []PlayerStat) {
+	switch {
+	case stats.Outcome == models.Separated || stats.Outcome == models.TwoPlayers:
+		min, max := findMinAndMaxVotes(stats.Players)
+		leastVoted, neutral, mostVoted = findPlayerPositions(stats.Players, min, max)
+	case rule == models.TieMostVoted:
+		mostVoted = stats.Players
+	case rule == models.TieNeutral && stats.Outcome == models.AllTied:
+		neutral = stats.Players
+	default:
+		unplaced = stats.Players
+	}
+	return leastVoted, neutral, mostVoted, unplaced
+}
+
+// findOutcome finds out if the votes separates the players or if they are tied.
+// Two players with different votes are always separated into most and least voted.
+func findOutcome(playerStats []PlayerStat) models.ScoringOutcome {
+	min, max := findMinAndMaxVotes(playerStats)
+	switch {
+	case max == 0:
+		return models.NoVotes
+	case min == max:
+		return models.AllTied
+	case len(playerStats) == 2:
+		return models.TwoPlayers
+	default:
+		return models.Separated
+	}
+}
+
+func findMinAndMaxVotes(playerStats []PlayerStat) (min, max int) {
+	if len(playerStats) == 0 {
+		return 0, 0
+	}
+	min = playerStats[0].Votes
+	max = playerStats[0].Votes
+	for _, playerStat := range playerStats {
+		if playerStat.Votes < min {
+			min = playerStat.Votes
+		}
+		if playerStat.Votes > max {
+			max = playerStat.Votes
+		}
+	}
+	return min, max
+}
+
+func findPlayerPositions(playerStats []PlayerStat, min, max int) (leastVoted, neutral, mostVoted []PlayerStat) {
+	for _, playerStat := range playerStats {
+		if playerStat.Votes == max {
+			mostVoted = append(mostVoted, playerStat)
+		} else if playerStat.Votes == min {
+			leastVoted = append(leastVoted, playerStat)
+		} else {
+			neutral = append(neutral, playerStat)
+		}
+	}
+	return leastVoted, neutral, mostVoted
+}
+
+// givePoints gives points to the players who guessed their position, the unplaced players get no points
+func givePoints(leastVoted, neutral, mostVoted, unplaced []PlayerStat) models.QuestionPoints {
+	var qp models.QuestionPoints
+
+	calculatePoints := func(s []PlayerStat, sv SelfVote, pointToGiveOnCorrect int) {
+		for _, p := range s {
+			pe := models.PointsEntry{Player: p.Name, SelfVote: string(p.SelfVote), VotesReceived: p.Votes, Position: string(sv)}
+			qp = append(qp, pe)
+			if p.SelfVote == sv {
+				qp[len(qp)-1].Points = pointToGiveOnCorrect
+				qp[len(qp)-1].Correct = true
+			} else {
+				qp[len(qp)-1].Points = WrongVotedPoints
+			}
+		}
+	}
+	calculatePoints(leastVoted, LeastVoted, LeastVotedPoints)
+	calculatePoints(neutral, Neutral, NeutralPoints)
+	calculatePoints(mostVoted, MostVoted, MostVotedPoints)
+	for _, p := range unplaced {
+		qp = append(qp, models.PointsEntry{Player: p.Name, SelfVote: string(p.SelfVote), VotesReceived: p.Votes, Points: WrongVotedPoints})
+	}
+	return qp
+}
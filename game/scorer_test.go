@@ -0,0 +1,106 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelfAssessmentScorerTieRules(t *testing.T) {
+	allTied := []PlayerStat{{Name: p1, Votes: 2, SelfVote: MostVoted}, {Name: p2, Votes: 2, SelfVote: Neutral}, {Name: p3, Votes: 2, SelfVote: LeastVoted}}
+	noVotes := []PlayerStat{{Name: p1, Votes: 0, SelfVote: MostVoted}, {Name: p2, Votes: 0, SelfVote: Neutral}, {Name: p3, Votes: 0, SelfVote: LeastVoted}}
+	twoPlayers := []PlayerStat{{Name: p1, Votes: 3, SelfVote: MostVoted}, {Name: p2, Votes: 1, SelfVote: Neutral}}
+
+	var tests = []struct {
+		testName        string
+		stats           []PlayerStat
+		rule            models.TieRule
+		expectedOutcome models.ScoringOutcome
+		expectedPoints  map[string]int
+	}{
+		{"all tied are neutral", allTied, models.TieNeutral, models.AllTied, map[string]int{p1: 0, p2: NeutralPoints, p3: 0}},
+		{"all tied are most voted", allTied, models.TieMostVoted, models.AllTied, map[string]int{p1: MostVotedPoints, p2: 0, p3: 0}},
+		{"all tied gives no points", allTied, models.TieNoPoints, models.AllTied, map[string]int{p1: 0, p2: 0, p3: 0}},
+		{"no votes gives no points", noVotes, models.TieNeutral, models.NoVotes, map[string]int{p1: 0, p2: 0, p3: 0}},
+		{"no votes are most voted", noVotes, models.TieMostVoted, models.NoVotes, map[string]int{p1: MostVotedPoints, p2: 0, p3: 0}},
+		{"two players are never neutral", twoPlayers, models.TieNeutral, models.TwoPlayers, map[string]int{p1: MostVotedPoints, p2: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			stats := QuestionStats{Players: tt.stats, Outcome: findOutcome(tt.stats)}
+			qp := SelfAssessmentScorer{TieRule: tt.rule}.Score(stats)
+			assert.Equal(t, tt.expectedOutcome, stats.Outcome)
+			assert.Len(t, qp, len(tt.expectedPoints))
+			for _, entry := range qp {
+				assert.Equal(t, tt.expectedPoints[entry.Player], entry.Points, entry.Player)
+			}
+		})
+	}
+}
+
+func TestProportionalScorer(t *testing.T) {
+	players := []PlayerStat{
+		{Name: p1, Votes: 4, SelfVote: MostVoted},
+		{Name: p2, Votes: 2, SelfVote: Neutral},
+		{Name: p3, Votes: 0, SelfVote: LeastVoted},
+	}
+	stats := QuestionStats{Players: players, Outcome: findOutcome(players)}
+	// an even share is two votes
+	expectedPoints := map[string]int{p1: 2 * MostVotedPoints, p2: NeutralPoints, p3: LeastVotedPoints}
+
+	for _, entry := range (ProportionalScorer{TieRule: models.TieNeutral}).Score(stats) {
+		assert.True(t, entry.Correct)
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+	}
+}
+
+func TestStreakScorer(t *testing.T) {
+	players := []PlayerStat{
+		{Name: p1, Votes: 4, SelfVote: MostVoted, Streak: 1},
+		{Name: p2, Votes: 2, SelfVote: MostVoted, Streak: 2},
+		{Name: p3, Votes: 0, SelfVote: LeastVoted, Streak: MaxStreakBonus + 2},
+	}
+	stats := QuestionStats{Players: players, Outcome: findOutcome(players)}
+	expectedPoints := map[string]int{
+		p1: MostVotedPoints + StreakBonusPoints,
+		p2: WrongVotedPoints,
+		p3: LeastVotedPoints + MaxStreakBonus*StreakBonusPoints,
+	}
+
+	for _, entry := range (StreakScorer{SelfAssessmentScorer{TieRule: models.TieNeutral}}).Score(stats) {
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+	}
+}
+
+func TestGame_StreakIsCountedFromLedger(t *testing.T) {
+	g := createTestableGame(t)
+	createFinishedGame(g, t)
+	// every player has guessed correctly in all the four questions
+	for _, p := range g.questionStats(g.currentQuestion, g.currentRecord()).Players {
+		assert.Equal(t, QuestionsPerRound, p.Streak, p.Name)
+	}
+}
+
+func TestNewScorer(t *testing.T) {
+	_, err := NewScorer(models.RoomSettings{Scoring: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+
+	s, err := NewScorer(models.RoomSettings{Scoring: models.StreakScoring, TieRule: models.TieNoPoints})
+	assert.NoError(t, err)
+	assert.IsType(t, StreakScorer{}, s)
+}
+
+func TestPredictionScorer(t *testing.T) {
+	exact, offByOne, farOff := 4, 3, 5
+	players := []PlayerStat{
+		{Name: p1, Votes: 4, Prediction: &exact},
+		{Name: p2, Votes: 2, Prediction: &offByOne},
+		{Name: p3, Votes: 0, Prediction: &farOff},
+	}
+	expectedPoints := map[string]int{p1: PredictionPoints, p2: PredictionPoints - 1, p3: WrongVotedPoints}
+
+	for _, entry := range (PredictionScorer{}).Score(QuestionStats{Players: players}) {
+		assert.Equal(t, expectedPoints[entry.Player], entry.Points, entry.Player)
+		assert.Equal(t, entry.Player == p1, entry.Correct, entry.Player)
+	}
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"time"
+)
+
+const (
+	// a game with less than two players makes every player the most voted
+	LowestMinPlayers  = 2
+	HighestMaxPlayers = 50
+	DefaultMinPlayers = 3
+	DefaultMaxPlayers = 16
+)
+
+var ErrInvalidSettings = errors.New("invalid room settings")
+
+// ValidateSettings returns the settings with defaults applied,
+// or an error if the settings can not be used for a game
+func ValidateSettings(s models.RoomSettings) (models.RoomSettings, error) {
+	s = withDefaults(s)
+	if s.MinPlayers < LowestMinPlayers {
+		return s, fmt.Errorf("%w: minimum players must be at least %d", ErrInvalidSettings, LowestMinPlayers)
+	}
+	if s.MaxPlayers > HighestMaxPlayers {
+		return s, fmt.Errorf("%w: maximum players can not be more than %d", ErrInvalidSettings, HighestMaxPlayers)
+	}
+	if s.MinPlayers > s.MaxPlayers {
+		return s, fmt.Errorf("%w: minimum players (%d) is larger than maximum players (%d)", ErrInvalidSettings, s.MinPlayers, s.MaxPlayers)
+	}
+	switch s.TieRule {
+	case models.TieNeutral, models.TieMostVoted, models.TieNoPoints:
+	default:
+		return s, fmt.Errorf("%w: unknown tie rule '%s'", ErrInvalidSettings, s.TieRule)
+	}
+	switch s.Voting {
+	case models.AnonymousVoting, models.RevealedVoting:
+	default:
+		return s, fmt.Errorf("%w: unknown voting mode '%s'", ErrInvalidSettings, s.Voting)
+	}
+	switch s.History {
+	case "", models.RoomHistory, models.PlayersHistory:
+	default:
+		return s, fmt.Errorf("%w: unknown history mode '%s'", ErrInvalidSettings, s.History)
+	}
+	if s.Teams != 0 && (s.Teams < LowestTeams || s.Teams > HighestTeams) {
+		return s, fmt.Errorf("%w: teams must be between %d and %d", ErrInvalidSettings, LowestTeams, HighestTeams)
+	}
+	if s.Teams > s.MaxPlayers {
+		return s, fmt.Errorf("%w: more teams (%d) than maximum players (%d)", ErrInvalidSettings, s.Teams, s.MaxPlayers)
+	}
+	if s.Language != "" {
+		if err := question.ValidateLanguage(s.Language); err != nil {
+			return s, fmt.Errorf("%w: %s", ErrInvalidSettings, err)
+		}
+	}
+	if s.MaxRating != "" {
+		if err := question.ValidateRating(s.MaxRating); err != nil {
+			return s, fmt.Errorf("%w: %s", ErrInvalidSettings, err)
+		}
+	}
+	for _, category := range s.Categories {
+		if category == "" {
+			return s, fmt.Errorf("%w: a category can not be empty", ErrInvalidSettings)
+		}
+	}
+	if _, err := NewScorer(s); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+func withDefaults(s models.RoomSettings) models.RoomSettings {
+	if s.MinPlayers == 0 {
+		s.MinPlayers = DefaultMinPlayers
+	}
+	if s.MaxPlayers == 0 {
+		s.MaxPlayers = DefaultMaxPlayers
+	}
+	if s.TieRule == "" {
+		s.TieRule = models.TieNeutral
+	}
+	if s.Scoring == "" {
+		s.Scoring = models.SelfAssessmentScoring
+	}
+	if s.Voting == "" {
+		s.Voting = models.AnonymousVoting
+	}
+	if s.Seed == 0 {
+		s.Seed = time.Now().UnixNano()
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateSettings(t *testing.T) {
+	var tests = []struct {
+		testName  string
+		settings  models.RoomSettings
+		expectErr bool
+	}{
+		{"defaults are valid", models.RoomSettings{}, false},
+		{"two players are allowed", models.RoomSettings{MinPlayers: 2, MaxPlayers: 2}, false},
+		{"one player is not enough", models.RoomSettings{MinPlayers: 1}, true},
+		{"too many players", models.RoomSettings{MaxPlayers: HighestMaxPlayers + 1}, true},
+		{"min is larger than max", models.RoomSettings{MinPlayers: 5, MaxPlayers: 4}, true},
+		{"revealed voting", models.RoomSettings{Voting: models.RevealedVoting}, false},
+		{"two teams", models.RoomSettings{Teams: 2}, false},
+		{"one team is not a team game", models.RoomSettings{Teams: 1}, true},
+		{"more teams than players", models.RoomSettings{Teams: 4, MaxPlayers: 3}, true},
+		{"norwegian bokmål", models.RoomSettings{Language: "nb-NO"}, false},
+		{"language is not a language tag", models.RoomSettings{Language: "norwegian"}, true},
+		{"safe questions at work", models.RoomSettings{Categories: []string{"humor"}, MaxRating: models.SafeRating}, false},
+		{"unknown content rating", models.RoomSettings{MaxRating: "PG-13"}, true},
+		{"unknown voting mode", models.RoomSettings{Voting: "PUBLIC"}, true},
+		{"history of the players", models.RoomSettings{History: models.PlayersHistory}, false},
+		{"unknown history mode", models.RoomSettings{History: "FOREVER"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			s, err := ValidateSettings(tt.settings)
+			if tt.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidSettings)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotZero(t, s.MinPlayers)
+			assert.NotZero(t, s.MaxPlayers)
+			assert.NotEmpty(t, s.Voting)
+			assert.NotZero(t, s.Seed, "the seed is recorded in the settings")
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"sort"
+)
+
+// MinQuestionsForAlwaysVotesFor is the least number of questions a player must have voted for the same player
+// to get the always votes for award
+const MinQuestionsForAlwaysVotesFor = 2
+
+// selfVoteRank orders the self votes so a guess can be compared to the position of the player
+var selfVoteRank = map[string]int{string(LeastVoted): 0, string(Neutral): 1, string(MostVoted): 2}
+
+// Stats computes the player statistics and awards from the questions which are done.
+// Awards showing who voted for whom are only given when the game uses RevealedVoting.
+func (g *Game) Stats() models.GameStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	players := make(map[string]*models.PlayerGameStats)
+	for name := range g.players {
+		players[name] = &models.PlayerGameStats{Player: name}
+	}
+	underrated := make(map[string]int)
+	overrated := make(map[string]int)
+	// votedFor counts the questions where the voter voted for the receiver
+	votedFor := make(map[[2]string]int)
+	var stats models.GameStats
+
+	for q := FirstQuestionNumber; q < g.currentQuestion; q++ {
+		record, ok := g.ledger[q]
+		if !ok || !record.done {
+			continue
+		}
+		for _, entry := range record.points {
+			ps, exist := players[entry.Player]
+			if !exist {
+				continue
+			}
+			ps.QuestionsPlayed++
+			ps.VotesReceived += entry.VotesReceived
+			if entry.Correct {
+				ps.CorrectGuesses++
+			}
+			if entry.Position == string(MostVoted) {
+				ps.TimesMostVoted++
+			}
+			switch difference := guessDifference(entry); {
+			case difference > 0:
+				underrated[entry.Player]++
+			case difference < 0:
+				overrated[entry.Player]++
+			}
+		}
+		if questionType(record.question) != models.MostLikelyQuestion {
+			continue
+		}
+		for voter, receivers := range record.votes {
+			seen := make(map[string]bool)
+			for _, receiver := range receivers {
+				if !seen[receiver] && voter != receiver {
+					seen[receiver] = true
+					votedFor[[2]string{voter, receiver}]++
+				}
+			}
+		}
+		if h, ok := questionHighlight(q, record); ok {
+			stats.Highlights = append(stats.Highlights, h)
+		}
+	}
+
+	selfAware := make(map[string]int)
+	for name, ps := range players {
+		selfAware[name] = ps.CorrectGuesses
+		stats.Players = append(stats.Players, *ps)
+	}
+	sort.Slice(stats.Players, func(i, j int) bool { return stats.Players[i].Player < stats.Players[j].Player })
+
+	if a, ok := topAward(models.MostSelfAware, selfAware); ok {
+		stats.Awards = append(stats.Awards, a)
+	}
+	if a, ok := topAward(models.MostUnderrated, underrated); ok {
+		stats.Awards = append(stats.Awards, a)
+	}
+	if a, ok := topAward(models.MostOverrated, overrated); ok {
+		stats.Awards = append(stats.Awards, a)
+	}
+	if g.settings.Voting == models.RevealedVoting {
+		if a, ok := g.alwaysVotesFor(votedFor); ok {
+			stats.Awards = append(stats.Awards, a)
+		}
+	}
+	sortHighlights(stats.Highlights)
+	return stats
+}
+
+// guessDifference is positive when the player received more votes than guessed,
+// and negative when the player received fewer votes than guessed
+func guessDifference(entry models.PointsEntry) int {
+	if entry.Prediction != nil {
+		return entry.VotesReceived - *entry.Prediction
+	}
+	position, placed := selfVoteRank[entry.Position]
+	guess, guessed := selfVoteRank[entry.SelfVote]
+	if !placed || !guessed {
+		return 0
+	}
+	return position - guess
+}
+
+// topAward gives the award to the player with the highest value, ties go to the first name in alphabetical order
+func topAward(award models.AwardType, values map[string]int) (models.Award, bool) {
+	var best models.Award
+	for name, value := range values {
+		if value > best.Value || (value == best.Value && value > 0 && name < best.Player) {
+			best = models.Award{Award: award, Player: name, Value: value}
+		}
+	}
+	return best, best.Value > 0
+}
+
+// alwaysVotesFor finds the player who voted for the same player in the most questions, g.mu must be held
+func (g *Game) alwaysVotesFor(votedFor map[[2]string]int) (models.Award, bool) {
+	var best models.Award
+	for pair, count := range votedFor {
+		voter, receiver := pair[0], pair[1]
+		if _, exist := g.players[voter]; !exist {
+			continue
+		}
+		// ties go to the first voter by name, and then the first receiver by name
+		tied := count == best.Value
+		if count > best.Value || (tied && voter < best.Player) || (tied && voter == best.Player && receiver < best.Target) {
+			best = models.Award{Award: models.AlwaysVotesFor, Player: voter, Target: receiver, Value: count}
+		}
+	}
+	return best, best.Value >= MinQuestionsForAlwaysVotesFor
+}
+
+// questionHighlight finds the most voted player and the share of the votes the player received
+func questionHighlight(question int, record *questionRecord) (models.QuestionHighlight, bool) {
+	var total int
+	var top models.PointsEntry
+	for _, entry := range record.points {
+		total += entry.VotesReceived
+		if entry.VotesReceived > top.VotesReceived || (entry.VotesReceived == top.VotesReceived && entry.Player < top.Player) {
+			top = entry
+		}
+	}
+	if total == 0 {
+		return models.QuestionHighlight{}, false
+	}
+	return models.QuestionHighlight{
+		Question:      question,
+		QuestionId:    record.question.Id,
+		Player:        top.Player,
+		Concentration: float64(top.VotesReceived) / float64(total),
+	}, true
+}
+
+// sortHighlights puts the questions where the votes were most concentrated first,
+// the first highlight is the most unanimous question and the last is the most divided
+func sortHighlights(highlights []models.QuestionHighlight) {
+	sort.SliceStable(highlights, func(i, j int) bool {
+		return highlights[i].Concentration > highlights[j].Concentration
+	})
+}
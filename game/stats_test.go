@@ -0,0 +1,69 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// createGameForStats plays two questions, all players guess correctly in the first question,
+// in the second question the most voted player guesses least voted and the neutral player guesses most voted
+func createGameForStats(t *testing.T) *Game {
+	g := createTestableGame(t)
+	g.SetVotesFromPlayer(createTwoVotes(p1, p2))
+	g.SetVotesFromPlayer(createTwoVotes(p2, p1))
+	g.SetVotesFromPlayer(createTwoVotes(p3, p1))
+	g.SetSelfVoteFromPlayer(createSelfVote(p1, LeastVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p2, MostVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p3, LeastVoted))
+	questionDone, _ := g.IsRoundFinished()
+	assert.True(t, questionDone)
+	return g
+}
+
+func TestStats(t *testing.T) {
+	g := createGameForStats(t)
+	stats := g.Stats()
+
+	assert.Equal(t, []models.PlayerGameStats{
+		{Player: p1, QuestionsPlayed: 2, CorrectGuesses: 1, VotesReceived: 8, TimesMostVoted: 2},
+		{Player: p2, QuestionsPlayed: 2, CorrectGuesses: 1, VotesReceived: 4},
+		{Player: p3, QuestionsPlayed: 2, CorrectGuesses: 2},
+	}, stats.Players)
+	assert.Equal(t, []models.Award{
+		{Award: models.MostSelfAware, Player: p3, Value: 2},
+		{Award: models.MostUnderrated, Player: p1, Value: 1},
+		{Award: models.MostOverrated, Player: p2, Value: 1},
+	}, stats.Awards, "who voted for whom is not shown with anonymous voting")
+	assert.Len(t, stats.Highlights, 2)
+	assert.Equal(t, p1, stats.Highlights[0].Player)
+	assert.InDelta(t, 4.0/6.0, stats.Highlights[0].Concentration, 0.001)
+}
+
+func TestStatsRevealedVoting(t *testing.T) {
+	g := createGameForStats(t)
+	g.settings.Voting = models.RevealedVoting
+
+	assert.Contains(t, g.Stats().Awards, models.Award{Award: models.AlwaysVotesFor, Player: p1, Target: p2, Value: 2})
+}
+
+func TestStatsWithoutQuestions(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer(p1)
+	stats := g.Stats()
+	assert.Empty(t, stats.Awards)
+	assert.Empty(t, stats.Highlights)
+	assert.Equal(t, []models.PlayerGameStats{{Player: p1}}, stats.Players)
+}
+
+func TestAlwaysVotesForTieBreak(t *testing.T) {
+	g := NewGame(TestQuestions, models.RoomSettings{})
+	g.AddPlayer("a")
+	g.AddPlayer("ab")
+	votedFor := map[[2]string]int{{"ab", "c"}: 2, {"a", "bc"}: 2, {"a", "c"}: 2}
+	for i := 0; i < 10; i++ {
+		award, _ := g.alwaysVotesFor(votedFor)
+		assert.Equal(t, "a", award.Player, "the voter decides the tie before the receiver")
+		assert.Equal(t, "bc", award.Target)
+	}
+}
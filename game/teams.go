@@ -0,0 +1,164 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"sort"
+)
+
+const (
+	LowestTeams  = 2
+	HighestTeams = 8
+	// TeamBonusPoints are given to a team when every player on the team guessed their placement correctly
+	TeamBonusPoints = 2
+)
+
+var ErrNoTeams = errors.New("the game is played without teams")
+
+// teamNames returns the names of the teams in the order they are filled
+func teamNames(count int) []string {
+	var names []string
+	for i := 1; i <= count; i++ {
+		names = append(names, fmt.Sprintf("Team %d", i))
+	}
+	return names
+}
+
+// Teams returns the names of the teams, it is empty when the game is played without teams
+func (g *Game) Teams() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return teamNames(g.settings.Teams)
+}
+
+// SetPlayerTeam moves the player to the team, teams can only be changed in the lobby
+func (g *Game) SetPlayerTeam(playerName, team string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.canChangeTeams(); err != nil {
+		return err
+	}
+	p, exist := g.players[playerName]
+	if !exist {
+		return fmt.Errorf("unable to find a player with the name '%s', when setting team", playerName)
+	}
+	for _, name := range teamNames(g.settings.Teams) {
+		if name == team {
+			p.team = team
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown team '%s'", team)
+}
+
+// BalanceTeams lets the host shuffle the players into teams of the same size, give or take one player
+func (g *Game) BalanceTeams(playerName string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if playerName != g.host {
+		return ErrNotHost
+	}
+	if err := g.canChangeTeams(); err != nil {
+		return err
+	}
+	var names []string
+	for name := range g.players {
+		names = append(names, name)
+	}
+	// the names are sorted first so the teams only depend on the seed of the game
+	sort.Strings(names)
+	g.rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+	teams := teamNames(g.settings.Teams)
+	for i, name := range names {
+		g.players[name].team = teams[i%len(teams)]
+	}
+	return nil
+}
+
+func (g *Game) canChangeTeams() error {
+	if g.settings.Teams == 0 {
+		return ErrNoTeams
+	}
+	if g.started {
+		return errors.New("teams can not be changed after the game has started")
+	}
+	return nil
+}
+
+// smallestTeam returns the team with the fewest players, or an empty string when playing without teams.
+// g.mu must be held.
+func (g *Game) smallestTeam() string {
+	teams := teamNames(g.settings.Teams)
+	if len(teams) == 0 {
+		return ""
+	}
+	size := make(map[string]int)
+	for _, p := range g.players {
+		size[p.team]++
+	}
+	smallest := teams[0]
+	for _, team := range teams[1:] {
+		if size[team] < size[smallest] {
+			smallest = team
+		}
+	}
+	return smallest
+}
+
+// teamBonus finds the teams where every player guessed their placement correctly, g.mu must be held
+func (g *Game) teamBonus(record *questionRecord) map[string]int {
+	if g.settings.Teams == 0 || !g.settings.TeamBonus {
+		return nil
+	}
+	allCorrect := make(map[string]bool)
+	for _, entry := range record.points {
+		team := record.teams[entry.Player]
+		if correct, seen := allCorrect[team]; !seen || correct {
+			allCorrect[team] = entry.Correct
+		}
+	}
+	bonus := make(map[string]int)
+	for team, correct := range allCorrect {
+		if correct && team != "" {
+			bonus[team] = TeamBonusPoints
+		}
+	}
+	return bonus
+}
+
+// CalculateTeamPoints calculates the team points from the given range of questions.
+// The points a player earned stay with the team the player was on, also after the player left the game.
+func (g *Game) CalculateTeamPoints(from, to int) []models.TeamPointsEntry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	teams := teamNames(g.settings.Teams)
+	if len(teams) == 0 {
+		return nil
+	}
+	points := make(map[string]int)
+	bonus := make(map[string]int)
+	for i := from; i <= to; i++ {
+		record, ok := g.ledger[i]
+		if !ok || !record.done {
+			continue
+		}
+		for _, entry := range record.points {
+			points[record.teams[entry.Player]] += entry.Points
+		}
+		for team, b := range record.teamBonus {
+			bonus[team] += b
+		}
+	}
+	var tpes []models.TeamPointsEntry
+	for _, team := range teams {
+		tpes = append(tpes, models.TeamPointsEntry{
+			Team:   team,
+			Points: points[team] + bonus[team],
+			Bonus:  bonus[team],
+		})
+	}
+	sort.SliceStable(tpes, func(i, j int) bool { return tpes[i].Points > tpes[j].Points })
+	return tpes
+}
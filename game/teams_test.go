@@ -0,0 +1,88 @@
+package game
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func createTeamGame(settings models.RoomSettings, players ...string) *Game {
+	g := NewGame(TestQuestions, settings)
+	for _, p := range players {
+		g.AddPlayer(p)
+	}
+	return &g
+}
+
+func teamSizes(g *Game) map[string]int {
+	sizes := make(map[string]int)
+	players, _ := g.GetRoomStatus()
+	for _, p := range players {
+		sizes[p.Team]++
+	}
+	return sizes
+}
+
+func TestAddPlayerJoinsSmallestTeam(t *testing.T) {
+	g := createTeamGame(models.RoomSettings{Teams: 2}, p1, p2, p3, "Player DDD")
+	assert.Equal(t, map[string]int{"Team 1": 2, "Team 2": 2}, teamSizes(g))
+
+	g = createTeamGame(models.RoomSettings{}, p1, p2)
+	assert.Equal(t, map[string]int{"": 2}, teamSizes(g), "players have no team without teams")
+	assert.Empty(t, g.Teams())
+}
+
+func TestSetPlayerTeam(t *testing.T) {
+	g := createTeamGame(models.RoomSettings{Teams: 2}, p1, p2)
+	assert.NoError(t, g.SetPlayerTeam(p2, "Team 1"))
+	assert.Equal(t, map[string]int{"Team 1": 2}, teamSizes(g))
+	assert.Error(t, g.SetPlayerTeam(p1, "Team 3"))
+	assert.Error(t, g.SetPlayerTeam("unknown", "Team 1"))
+
+	g.started = true
+	assert.Error(t, g.SetPlayerTeam(p1, "Team 2"), "teams can not be changed after the game has started")
+
+	g = createTeamGame(models.RoomSettings{}, p1)
+	assert.ErrorIs(t, g.SetPlayerTeam(p1, "Team 1"), ErrNoTeams)
+}
+
+func TestBalanceTeams(t *testing.T) {
+	g := createTeamGame(models.RoomSettings{Teams: 2}, p1, p2, p3)
+	assert.NoError(t, g.SetPlayerTeam(p2, "Team 1"))
+	assert.NoError(t, g.SetPlayerTeam(p3, "Team 1"))
+
+	assert.ErrorIs(t, g.BalanceTeams(p2), ErrNotHost, "only the host can reshuffle the teams")
+	assert.Equal(t, map[string]int{"Team 1": 3}, teamSizes(g), "the teams are unchanged")
+
+	assert.NoError(t, g.BalanceTeams(p1))
+	sizes := teamSizes(g)
+	assert.Len(t, sizes, 2)
+	assert.InDelta(t, sizes["Team 1"], sizes["Team 2"], 1)
+}
+
+func TestCalculateTeamPoints(t *testing.T) {
+	g := createTeamGame(models.RoomSettings{Teams: 2, TeamBonus: true}, p1, p2, p3)
+	assert.NoError(t, g.SetPlayerTeam(p1, "Team 1"))
+	assert.NoError(t, g.SetPlayerTeam(p2, "Team 2"))
+	assert.NoError(t, g.SetPlayerTeam(p3, "Team 2"))
+
+	g.SetVotesFromPlayer(createTwoVotes(p1, p2))
+	g.SetVotesFromPlayer(createTwoVotes(p2, p1))
+	g.SetVotesFromPlayer(createTwoVotes(p3, p1))
+	g.SetSelfVoteFromPlayer(createSelfVote(p1, MostVoted))
+	g.SetSelfVoteFromPlayer(createSelfVote(p2, Neutral))
+	g.SetSelfVoteFromPlayer(createSelfVote(p3, MostVoted))
+	questionDone, _ := g.IsRoundFinished()
+	assert.True(t, questionDone)
+
+	expected := []models.TeamPointsEntry{
+		{Team: "Team 1", Points: MostVotedPoints + TeamBonusPoints, Bonus: TeamBonusPoints},
+		{Team: "Team 2", Points: NeutralPoints},
+	}
+	assert.Equal(t, expected, g.CalculateTeamPoints(FirstQuestionNumber, g.GetCurrentDoneQuestion()))
+
+	g.RemovePlayer(p1)
+	assert.Equal(t, expected, g.CalculateTeamPoints(FirstQuestionNumber, g.GetCurrentDoneQuestion()),
+		"the points stay with the team after a player left")
+	assert.Nil(t, createTeamGame(models.RoomSettings{}, p1).CalculateTeamPoints(FirstQuestionNumber, 1))
+}
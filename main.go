@@ -1,27 +1,52 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/akselleirv/introspect/game"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
 	"github.com/akselleirv/introspect/server"
 	"github.com/gorilla/websocket"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// PacksDir is the directory with the question packs, it is used when QUESTIONS_DB is not set
+	PacksDir = "./packs"
+	// ReloadInterval is how often the question packs are checked for changes
+	ReloadInterval = 10 * time.Second
 )
 
 var upgrader = websocket.Upgrader{}
 
 func main() {
-	s := server.NewServer()
+	questions := questionSource()
+	s := server.NewServer(questions)
+	if reloader, ok := questions.(question.Reloader); ok {
+		reloadOnSignal(reloader)
+	}
 
 	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
 
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		playerName, room := getParams(r)
-		if playerName == "" || room == "" {
+		join := getJoinParams(r)
+		if join.Player == "" || join.Room == "" {
 			fmt.Fprint(w, "room name or playername is missing from the URL")
 			return
 		}
+		if join.Role != models.PlayerRole && join.Role != models.SpectatorRole {
+			fmt.Fprint(w, "role must be either player or spectator")
+			return
+		}
 
 		c, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -29,30 +54,51 @@ func main() {
 			return
 		}
 
-		s.NewConn(c, playerName, room)
+		s.NewConn(c, join)
 	})
 
+	http.HandleFunc("/rooms", createRoomHandler(s))
+
 	http.HandleFunc("/validateGameInfo", func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		playerName, room := getParams(req)
-		if playerName == "" || room == "" {
+		join := getJoinParams(req)
+		if join.Player == "" || join.Room == "" {
 			fmt.Fprint(w, "room name or playername is missing from the URL")
 			return
 		}
 
-		p, r := s.IsGameInfoValid(room, playerName)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(struct {
-			PlayerNameAvailable bool `json:"playerNameAvailable"`
-			RoomIsJoinable      bool `json:"roomIsJoinable"`
-		}{
-			PlayerNameAvailable: p,
-			RoomIsJoinable:      r,
-		})
+		json.NewEncoder(w).Encode(s.IsGameInfoValid(join))
 		return
 	})
 
+	http.HandleFunc("/admin/reload", func(w http.ResponseWriter, req *http.Request) {
+		token := os.Getenv("ADMIN_TOKEN")
+		// the token is compared in constant time, like the room passwords, so the comparison does not leak it
+		if token == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		reloader, ok := questions.(question.Reloader)
+		if !ok {
+			http.Error(w, "the questions are read from the database each round, there is nothing to reload", http.StatusNotImplemented)
+			return
+		}
+		if err := reloader.Reload(); err != nil {
+			log.Println("unable to reload the question packs: ", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		log.Println("reloaded the question packs")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
 	http.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "pong")
 	})
@@ -61,6 +107,80 @@ func main() {
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
+// createRoomHandler creates a room from the settings in the request body and responds with the room code
+func createRoomHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if req.Method == http.MethodOptions {
+			return
+		}
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var createRoom models.CreateRoom
+		if err := json.NewDecoder(req.Body).Decode(&createRoom); err != nil && err != io.EOF {
+			http.Error(w, "unable to parse request body", http.StatusBadRequest)
+			return
+		}
+
+		created, err := s.CreateRoom(createRoom)
+		if errors.Is(err, server.ErrRoomNameTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if errors.Is(err, game.ErrInvalidSettings) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Println("unable to create room: ", err)
+			http.Error(w, "unable to create room", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	}
+}
+
+// questionSource returns the SQLite database in QUESTIONS_DB when it is set, otherwise the packs in PacksDir.
+// The packs are reloaded when the files change.
+func questionSource() question.Source {
+	path := os.Getenv("QUESTIONS_DB")
+	if path == "" {
+		catalog, err := question.NewCatalog(PacksDir, question.NewMemoryHistory())
+		if err != nil {
+			log.Fatal("unable to load the question packs: ", err)
+		}
+		go catalog.Watch(ReloadInterval, nil)
+		return catalog
+	}
+	db, err := question.OpenSQLite(path)
+	if err != nil {
+		log.Fatal("unable to open the questions database: ", err)
+	}
+	log.Println("serving questions from", path)
+	return question.SQLiteSource{DB: db}
+}
+
+// reloadOnSignal reloads the questions when the server receives SIGHUP
+func reloadOnSignal(reloader question.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloader.Reload(); err != nil {
+				log.Println("unable to reload the question packs: ", err)
+				continue
+			}
+			log.Println("reloaded the question packs")
+		}
+	}()
+}
+
 // getParams returns playerName and roomName from the URL param
 func getParams(r *http.Request) (string, string) {
 	player, ok := r.URL.Query()["player"]
@@ -77,3 +197,19 @@ func getParams(r *http.Request) (string, string) {
 
 	return player[0], room[0]
 }
+
+// getJoinParams returns the information needed to join a room from the URL params
+func getJoinParams(r *http.Request) models.JoinRoom {
+	playerName, room := getParams(r)
+	role := models.Role(r.URL.Query().Get("role"))
+	if role == "" {
+		role = models.PlayerRole
+	}
+	return models.JoinRoom{
+		Player:   playerName,
+		Room:     room,
+		Password: r.URL.Query().Get("password"),
+		Create:   r.URL.Query().Get("create") == "true",
+		Role:     role,
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"github.com/akselleirv/introspect/server"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateRoomHandler(t *testing.T) {
+	h := createRoomHandler(server.NewServer(question.PackDir{Dir: "testPacks"}))
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodPost, "/rooms", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"name": "party", "password": "secret", "inviteOnly": true}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.RoomCreated
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "party", created.Name)
+	assert.NotEmpty(t, created.Code)
+
+	assert.Equal(t, http.StatusCreated, post("").Code, "the body is optional")
+	assert.Equal(t, http.StatusConflict, post(`{"name": "party"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"settings": {"minPlayers": 5, "maxPlayers": 4}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"name":`).Code)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/rooms", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
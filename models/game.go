@@ -4,17 +4,91 @@ type Questions struct {
 	Questions []Question `json:"questions"`
 }
 
+// PackManifest describes a question pack, it is read from the manifest file in the directory of the pack
+type PackManifest struct {
+	// Id is the name of the directory of the pack, it is not read from the manifest
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Languages are the BCP 47 tags of the languages the questions are translated to
+	Languages []string `json:"languages"`
+	Author    string   `json:"author"`
+	// Rating is the highest content rating of the questions in the pack
+	Rating ContentRating `json:"rating"`
+	// QuestionCount is counted when the pack is loaded, it is not read from the manifest
+	QuestionCount int `json:"questionCount"`
+}
+
+// SelectPacks is sent by the host in the lobby to choose the question packs to play with
+type SelectPacks struct {
+	Player string   `json:"player"`
+	Packs  []string `json:"packs"`
+}
+
+type PacksResponse struct {
+	Event string         `json:"event"`
+	Packs []PackManifest `json:"packs"`
+	// Selected are the ids of the packs the room plays with, all packs are played when it is empty
+	Selected []string `json:"selected"`
+}
+
 type Question struct {
 	Id string `json:"id"`
-	// Question value is the question translated based on the key.
-	// Currently supported languages are: 'no' and 'en'
+	// Question value is the question translated based on the key, the keys are BCP 47 language tags
 	// {"questions": {"en": "question", "no": "spørsmål"}}
 	Question QuestionTranslations `json:"question"`
+	// Text is the question in the language of the room, and Language is the language of the text.
+	// They are only set when the room has a language, or the question is a custom question.
+	Text     string `json:"text,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Type decides how the players vote on the question, an empty type is MostLikelyQuestion
+	Type QuestionType `json:"type,omitempty"`
+	// Options are the two options to choose between in a ThisOrThatQuestion
+	Options []QuestionTranslations `json:"options,omitempty"`
+	// Scale is the highest rating in a RatingQuestion, the lowest rating is 1
+	Scale int `json:"scale,omitempty"`
+	// VotesPerPlayer is the number of votes each player gives in a MostLikelyQuestion.
+	// When it is not set in the question, it is computed from the number of players when the question is sent.
+	VotesPerPlayer int `json:"votesPerPlayer,omitempty"`
+	// Categories are the topics of the question, rooms can choose which categories to play with
+	Categories []string `json:"categories,omitempty"`
+	// Tags describe the question, but are not used to choose questions
+	Tags   []string      `json:"tags,omitempty"`
+	Rating ContentRating `json:"rating,omitempty"`
+	// Author is the player who wrote a custom question
+	Author string `json:"author,omitempty"`
 }
-type QuestionTranslations struct {
-	Norwegian string `json:"no"`
-	English   string `json:"en"`
-}
+
+// ContentRating tells who the question is suitable for, the ratings are ordered from SafeRating to AdultRating
+type ContentRating string
+
+const (
+	// SafeRating questions can be played anywhere, also at work
+	SafeRating ContentRating = "SAFE"
+	// PartyRating questions are about drinking, dating and going out
+	PartyRating ContentRating = "PARTY"
+	// AdultRating questions are only for players who know each other well
+	AdultRating ContentRating = "ADULT"
+)
+
+type QuestionType string
+
+const (
+	// MostLikelyQuestion lets every player give two votes to the players most likely to fit the question
+	MostLikelyQuestion QuestionType = "MOST_LIKELY"
+	// RankingQuestion lets every player rank all the players,
+	// the players receive one vote for each player ranked below them
+	RankingQuestion QuestionType = "RANKING"
+	// RatingQuestion lets every player rate all the players on a scale,
+	// the players receive the sum of their ratings as votes
+	RatingQuestion QuestionType = "RATING"
+	// ThisOrThatQuestion lets every player choose one of two options,
+	// the players receive one vote for each player who chose the same option
+	ThisOrThatQuestion QuestionType = "THIS_OR_THAT"
+)
+
+// QuestionTranslations maps a BCP 47 language tag to the translation, an empty translation is missing
+type QuestionTranslations map[string]string
 
 type Vote struct {
 	PlayerWhoReceivedTheVote string `json:"playerWhoReceivedTheVote"`
@@ -23,20 +97,45 @@ type Vote struct {
 
 type PlayerVotedOnQuestion struct {
 	Player string `json:"player"`
-	Votes  []Vote `json:"votes"`
+	// Votes are used by a MostLikelyQuestion
+	Votes []Vote `json:"votes"`
+	// Ranking is all the players ordered from the first to the last, used by a RankingQuestion
+	Ranking []string `json:"ranking,omitempty"`
+	// Ratings maps every player to the rating given, used by a RatingQuestion
+	Ratings map[string]int `json:"ratings,omitempty"`
+	// Option is the index of the chosen option, used by a ThisOrThatQuestion
+	Option *int `json:"option,omitempty"`
 }
 
 type RegisterSelfVote struct {
-	Player   string   `json:"player"`
-	Choice   string   `json:"choice"`
-	Question Question `json:"question"`
+	Player string `json:"player"`
+	Choice string `json:"choice"`
+	// Prediction is the number of votes the player predicts to receive, it is only used with PredictionScoring
+	Prediction *int     `json:"prediction,omitempty"`
+	Question   Question `json:"question"`
+}
+
+// SelfVoting is sent when all players have voted on the question, and the players should vote on themselves
+type SelfVoting struct {
+	Event   string  `json:"event"`
+	Player  string  `json:"player"`
+	Scoring Scoring `json:"scoring"`
+	// MaxPrediction is the most votes a player can receive, it is only set with PredictionScoring
+	MaxPrediction int `json:"maxPrediction,omitempty"`
 }
 
 type PointsEntry struct {
 	Player        string `json:"player"`
 	SelfVote      string `json:"selfVote"`
 	VotesReceived int    `json:"votesReceived"`
-	Points        int    `json:"points"`
+	// Prediction is the number of votes the player predicted to receive, it is only set with PredictionScoring
+	Prediction *int `json:"prediction,omitempty"`
+	// Position is where the player was placed by the votes,
+	// it is empty when the question does not give any points
+	Position string `json:"position,omitempty"`
+	// Correct is true if the player guessed correctly
+	Correct bool `json:"correct"`
+	Points  int  `json:"points"`
 }
 
 type PointsEntrySimple struct {
@@ -47,6 +146,70 @@ type PlayersResults struct {
 	Event                        string              `json:"event"`
 	PlayersResultExceptLastRound []PointsEntrySimple `json:"playersResultExceptLastRound"`
 	PlayersResults               []PointsEntrySimple `json:"playersResults"`
+	// TeamResultsExceptLastRound and TeamResults are only set when the room plays with teams
+	TeamResultsExceptLastRound []TeamPointsEntry `json:"teamResultsExceptLastRound,omitempty"`
+	TeamResults                []TeamPointsEntry `json:"teamResults,omitempty"`
+	Stats                      GameStats         `json:"stats"`
+}
+
+// GameStats are the statistics and awards of the game, computed from the questions which are done
+type GameStats struct {
+	Players []PlayerGameStats `json:"players"`
+	Awards  []Award           `json:"awards"`
+	// Highlights are the questions ordered from the most unanimous to the most divided
+	Highlights []QuestionHighlight `json:"highlights"`
+}
+
+type GameStatsResponse struct {
+	Event string    `json:"event"`
+	Stats GameStats `json:"stats"`
+}
+
+type PlayerGameStats struct {
+	Player          string `json:"player"`
+	QuestionsPlayed int    `json:"questionsPlayed"`
+	CorrectGuesses  int    `json:"correctGuesses"`
+	VotesReceived   int    `json:"votesReceived"`
+	TimesMostVoted  int    `json:"timesMostVoted"`
+}
+
+type AwardType string
+
+const (
+	// MostSelfAware is given to the player with the most correct guesses
+	MostSelfAware AwardType = "MOST_SELF_AWARE"
+	// MostUnderrated is given to the player who most often received more votes than guessed
+	MostUnderrated AwardType = "MOST_UNDERRATED"
+	// MostOverrated is given to the player who most often received fewer votes than guessed
+	MostOverrated AwardType = "MOST_OVERRATED"
+	// AlwaysVotesFor is given to the player who voted for the same player in the most questions,
+	// it is only given with RevealedVoting since it shows who voted for whom
+	AlwaysVotesFor AwardType = "ALWAYS_VOTES_FOR"
+)
+
+type Award struct {
+	Award  AwardType `json:"award"`
+	Player string    `json:"player"`
+	// Target is the player the award is about, it is only set for AlwaysVotesFor
+	Target string `json:"target,omitempty"`
+	// Value is the number of questions the award was earned in
+	Value int `json:"value"`
+}
+
+type QuestionHighlight struct {
+	Question   int    `json:"question"`
+	QuestionId string `json:"questionId"`
+	// Player is the player who received the most votes
+	Player string `json:"player"`
+	// Concentration is the share of the votes the most voted player received, from 0 to 1
+	Concentration float64 `json:"concentration"`
+}
+
+type TeamPointsEntry struct {
+	Team string `json:"team"`
+	// Points are the points of the players on the team including the bonus
+	Points int `json:"points"`
+	Bonus  int `json:"bonus"`
 }
 
 type QuestionPoints []PointsEntry
@@ -56,6 +219,97 @@ type QuestionPointsEvent struct {
 	Event           string         `json:"event"`
 	QuestionPoints  QuestionPoints `json:"questionPoints"`
 	CurrentQuestion int            `json:"currentQuestion"`
+	// Scoring, TieRule and Outcome explains to the players how the question was scored
+	Scoring Scoring        `json:"scoring"`
+	TieRule TieRule        `json:"tieRule"`
+	Outcome ScoringOutcome `json:"outcome"`
+	Voting  VotingMode     `json:"voting"`
+	// RevealedVotes is only set with RevealedVoting
+	*RevealedVotes
+}
+
+// RevealedVotes shows who voted for whom, only the votes used by the type of the question are set
+type RevealedVotes struct {
+	// Votes maps each voter to the players who received the votes, or the players ranked by the voter
+	Votes VoteMatrix `json:"votes,omitempty"`
+	// Ratings maps each voter to the rating given to each player
+	Ratings map[string]map[string]int `json:"ratings,omitempty"`
+	// Options maps each voter to the option chosen
+	Options map[string]int `json:"options,omitempty"`
+}
+
+// VotingMode decides which votes are shown to the players
+type VotingMode string
+
+const (
+	// AnonymousVoting only reveals the number of votes each player received
+	AnonymousVoting VotingMode = "ANONYMOUS"
+	// RevealedVoting also reveals who voted for whom when the question is done
+	RevealedVoting VotingMode = "REVEALED"
+)
+
+// VoteMatrix maps the voter to the players who received the votes, or the players ranked by the voter
+type VoteMatrix map[string][]string
+
+// Scoring is the way points are given for the questions
+type Scoring string
+
+const (
+	// SelfAssessmentScoring gives points for guessing if you were most voted, neutral or least voted
+	SelfAssessmentScoring Scoring = "SELF_ASSESSMENT"
+	// ProportionalScoring gives points for a correct guess depending on the share of the votes
+	ProportionalScoring Scoring = "PROPORTIONAL"
+	// StreakScoring gives a bonus for correct guesses in a row
+	StreakScoring Scoring = "STREAK"
+	// PredictionScoring gives points for predicting how many votes you receive, the closer the more points
+	PredictionScoring Scoring = "PREDICTION"
+)
+
+// TieRule decides how a question is scored when the votes do not separate the players
+type TieRule string
+
+const (
+	// TieNeutral places everyone as neutral when all players received the same number of votes,
+	// and gives no points when nobody received any votes
+	TieNeutral TieRule = "NEUTRAL"
+	// TieMostVoted places everyone as most voted when all players received the same number of votes,
+	// also when nobody received any votes
+	TieMostVoted TieRule = "MOST_VOTED"
+	// TieNoPoints gives no points when all players received the same number of votes
+	TieNoPoints TieRule = "NO_POINTS"
+)
+
+type ScoringOutcome string
+
+const (
+	// Separated is the usual outcome, the players with the most votes are most voted,
+	// the players with the least votes are least voted and everyone in between are neutral
+	Separated ScoringOutcome = "SEPARATED"
+	// TwoPlayers are separated into most and least voted, since there is no one in between
+	TwoPlayers ScoringOutcome = "TWO_PLAYERS"
+	AllTied    ScoringOutcome = "ALL_TIED"
+	NoVotes    ScoringOutcome = "NO_VOTES"
+)
+
+type DeparturePolicy string
+
+// DiscardVotes withdraws the votes cast by the player who left, and discards the votes cast for the player.
+// It only applies to the question in progress, completed questions are not changed.
+const DiscardVotes DeparturePolicy = "DISCARD_VOTES"
+
+type RoundAdjusted struct {
+	Event          string          `json:"event"`
+	Player         string          `json:"player"`
+	Question       int             `json:"question"`
+	Policy         DeparturePolicy `json:"policy"`
+	VotesWithdrawn int             `json:"votesWithdrawn"`
+	VotesDiscarded int             `json:"votesDiscarded"`
+}
+
+// QuestionsExhausted is sent when there are no more questions, the game ends after the rounds which are played
+type QuestionsExhausted struct {
+	Event        string `json:"event"`
+	RoundsPlayed int    `json:"roundsPlayed"`
 }
 
 type ErrorMsg struct {
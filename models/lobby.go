@@ -5,6 +5,10 @@ type LobbyUpdateAction string
 const (
 	Joined LobbyUpdateAction = "JOINED"
 	Left                     = "LEFT"
+	// TeamsChanged is used when one or more players changed team
+	TeamsChanged LobbyUpdateAction = "TEAMS_CHANGED"
+	// PacksChanged is used when the host changed the question packs
+	PacksChanged LobbyUpdateAction = "PACKS_CHANGED"
 )
 
 type Ping struct {
@@ -25,10 +29,20 @@ type LobbyChat struct {
 }
 
 type LobbyRoomUpdate struct {
-	Event         string             `json:"event"`
-	Players       []PlayerUpdate     `json:"players"`
-	IsAllReady    bool               `json:"isAllReady"`
-	ActionTrigger LobbyActionTrigger `json:"actionTrigger,omitempty"`
+	Event      string         `json:"event"`
+	Players    []PlayerUpdate `json:"players"`
+	Spectators []string       `json:"spectators"`
+	IsAllReady bool           `json:"isAllReady"`
+	MinPlayers int            `json:"minPlayers"`
+	MaxPlayers int            `json:"maxPlayers"`
+	// Teams are the teams the players can join, it is empty when the room plays without teams
+	Teams []string `json:"teams,omitempty"`
+	// Packs are the question packs chosen by the host, all packs are played when it is empty
+	Packs []string `json:"packs,omitempty"`
+	// MaxRounds is the number of rounds the room can play before it runs out of new questions
+	MaxRounds        int                `json:"maxRounds"`
+	RecycleQuestions bool               `json:"recycleQuestions"`
+	ActionTrigger    LobbyActionTrigger `json:"actionTrigger,omitempty"`
 }
 
 type LobbyActionTrigger struct {
@@ -39,9 +53,48 @@ type LobbyActionTrigger struct {
 type PlayerUpdate struct {
 	Name    string `json:"name"`
 	IsReady bool   `json:"isReady"`
+	// IsQueued is true if the player joined a game in progress and waits for the next round
+	IsQueued bool   `json:"isQueued"`
+	Team     string `json:"team,omitempty"`
+	// IsHost is true for the player who can change the room in the lobby
+	IsHost bool `json:"isHost"`
+}
+
+// SetTeam is sent by a player in the lobby to join a team
+type SetTeam struct {
+	Player string `json:"player"`
+	Team   string `json:"team"`
+}
+
+type PlayersJoinedGame struct {
+	Event   string   `json:"event"`
+	Players []string `json:"players"`
 }
 
 type AddQuestion struct {
 	Player   string `json:"player"`
 	Question string `json:"question"`
+	// Language is the BCP 47 tag of the language the question is written in, the language of the room is used when it is empty
+	Language string `json:"language,omitempty"`
+}
+
+// ModerateCustomQuestion removes or approves the custom question with the id
+type ModerateCustomQuestion struct {
+	Player string `json:"player"`
+	Id     string `json:"id"`
+}
+
+// CustomQuestion is a question written by a player in the room
+type CustomQuestion struct {
+	Id       string `json:"id"`
+	Text     string `json:"text"`
+	Language string `json:"language"`
+	Author   string `json:"author"`
+	// Approved is false while the question waits for the host to approve it, only approved questions are played
+	Approved bool `json:"approved"`
+}
+
+type CustomQuestionsResponse struct {
+	Event     string           `json:"event"`
+	Questions []CustomQuestion `json:"questions"`
 }
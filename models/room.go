@@ -0,0 +1,119 @@
+package models
+
+type JoinRefusal string
+
+const (
+	PlayerNameTaken  JoinRefusal = "PLAYER_NAME_TAKEN"
+	RoomNotJoinable  JoinRefusal = "ROOM_NOT_JOINABLE"
+	PasswordRequired JoinRefusal = "PASSWORD_REQUIRED"
+	WrongPassword    JoinRefusal = "WRONG_PASSWORD"
+	InviteOnly       JoinRefusal = "INVITE_ONLY"
+	RoomNotFound     JoinRefusal = "ROOM_NOT_FOUND"
+	RoomFull         JoinRefusal = "ROOM_FULL"
+)
+
+type Role string
+
+const (
+	PlayerRole    Role = "player"
+	SpectatorRole Role = "spectator"
+)
+
+// JoinRoom is the information given by a client when joining a room
+type JoinRoom struct {
+	Player   string
+	Room     string
+	Password string
+	// Create the room if it does not exist
+	Create bool
+	Role   Role
+}
+
+// RoomAccess controls who is allowed to join a room
+type RoomAccess struct {
+	// Password is optional, an empty password means the room is open
+	Password string `json:"password"`
+	// InviteOnly rooms can only be joined with the generated room code, not by the room name
+	InviteOnly bool `json:"inviteOnly"`
+}
+
+// RoomSettings are the game settings chosen when creating the room.
+// Zero values are replaced by the defaults of the game package.
+type RoomSettings struct {
+	MinPlayers int     `json:"minPlayers"`
+	MaxPlayers int     `json:"maxPlayers"`
+	Scoring    Scoring `json:"scoring"`
+	TieRule    TieRule `json:"tieRule"`
+	// Voting decides if the players can see who voted for whom when a question is done
+	Voting VotingMode `json:"voting"`
+	// Teams is the number of teams the players are split into, zero plays without teams
+	Teams int `json:"teams"`
+	// Language is the BCP 47 tag of the language the questions are served in, like 'en' or 'nb-NO'.
+	// Questions without a translation falls back to English. All questions are served when it is empty.
+	Language string `json:"language"`
+	// Packs are the ids of the question packs to play with, all packs are played when it is empty
+	Packs []string `json:"packs,omitempty"`
+	// Categories are the question categories to play with, all categories are played when it is empty
+	Categories []string `json:"categories,omitempty"`
+	// MaxRating is the highest content rating of the questions, all questions are played when it is empty
+	MaxRating ContentRating `json:"maxRating,omitempty"`
+	// TeamBonus gives a team bonus when every player on the team guessed their placement correctly
+	TeamBonus bool `json:"teamBonus"`
+	// Seed decides the order of the questions, a game with the same seed and settings is served the same questions.
+	// A random seed is chosen when it is zero.
+	Seed int64 `json:"seed,omitempty"`
+	// History decides which earlier games are played by the same group, questions the group played recently are served less often.
+	// No history is used when it is empty.
+	History HistoryMode `json:"history,omitempty"`
+	// RecycleQuestions serves the questions played longest ago again when there are no new questions,
+	// otherwise the game ends when the questions run out
+	RecycleQuestions bool `json:"recycleQuestions"`
+	// ApproveCustomQuestions requires the host to approve the custom questions of the other players before they are played
+	ApproveCustomQuestions bool `json:"approveCustomQuestions"`
+}
+
+// HistoryMode decides how the games of a group are recognized
+type HistoryMode string
+
+const (
+	// RoomHistory treats games in rooms with the same name as the same group
+	RoomHistory HistoryMode = "ROOM"
+	// PlayersHistory treats games with the same players as the same group
+	PlayersHistory HistoryMode = "PLAYERS"
+)
+
+type CreateRoom struct {
+	// Name is optional, if set the room can also be joined by this name unless it is invite only
+	Name string `json:"name"`
+	RoomAccess
+	Settings RoomSettings `json:"settings"`
+}
+
+type RoomCreated struct {
+	Code     string       `json:"code"`
+	Name     string       `json:"name,omitempty"`
+	Settings RoomSettings `json:"settings"`
+}
+
+type GameSnapshot struct {
+	Event           string              `json:"event"`
+	Players         []PlayerUpdate      `json:"players"`
+	Spectators      []string            `json:"spectators"`
+	IsStarted       bool                `json:"isStarted"`
+	IsSelfVoting    bool                `json:"isSelfVoting"`
+	CurrentQuestion int                 `json:"currentQuestion"`
+	Questions       []Question          `json:"questions"`
+	Points          []PointsEntrySimple `json:"points"`
+	// Seed is the seed of the question order, the game can be replayed with it
+	Seed int64 `json:"seed"`
+}
+
+type GameInfo struct {
+	RoomExists          bool `json:"roomExists"`
+	PlayerNameAvailable bool `json:"playerNameAvailable"`
+	RoomIsJoinable      bool `json:"roomIsJoinable"`
+	RoomFull            bool `json:"roomFull"`
+	// JoinsNextRound is true when the game is in progress and the player will play from the next round
+	JoinsNextRound bool        `json:"joinsNextRound"`
+	Reason         JoinRefusal `json:"reason,omitempty"`
+}
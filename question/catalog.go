@@ -0,0 +1,208 @@
+package question
+
+import (
+	"errors"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader is a Source which can read its questions again without restarting the server
+type Reloader interface {
+	Reload() error
+}
+
+// Catalog is a Source with the packs in a directory which are read once and shared by all rooms.
+// The stores of the rooms are views of the questions in the catalog, they do not copy the questions.
+// A reload swaps in the new packs at once, the stores which are already created keep the questions they started with.
+type Catalog struct {
+	dir      string
+	history  History
+	snapshot atomic.Value // *snapshot
+	// mu makes sure only one reload reads the directory at the time
+	mu          sync.Mutex
+	fingerprint string
+}
+
+// NewCatalog loads the packs in dir, it returns an error if the packs can not be played.
+// The questions are weighted by the history, they are equally likely when it is nil.
+func NewCatalog(dir string, history History) (*Catalog, error) {
+	c := &Catalog{dir: dir, history: history}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the packs again, the packs in use are kept if the new packs can not be played
+func (c *Catalog) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fingerprint, err := dirFingerprint(c.dir)
+	if err != nil {
+		return err
+	}
+	packs, err := LoadPacks(c.dir)
+	if err != nil {
+		return err
+	}
+	if err := validatePacks(packs); err != nil {
+		return fmt.Errorf("keeping the current questions: %w", err)
+	}
+	c.snapshot.Store(newSnapshot(packs))
+	c.fingerprint = fingerprint
+	return nil
+}
+
+// Watch reloads the packs when a file in the directory has changed, it checks the directory each interval until done is closed
+func (c *Catalog) Watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			changed, err := c.changed()
+			if err != nil {
+				log.Println("unable to check the question packs: ", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := c.Reload(); err != nil {
+				log.Println("unable to reload the question packs: ", err)
+				continue
+			}
+			log.Println("reloaded the question packs in", c.dir)
+		}
+	}
+}
+
+func (c *Catalog) changed() (bool, error) {
+	fingerprint, err := dirFingerprint(c.dir)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fingerprint != c.fingerprint, nil
+}
+
+func (c *Catalog) current() *snapshot {
+	return c.snapshot.Load().(*snapshot)
+}
+
+func (c *Catalog) Packs() ([]models.PackManifest, error) {
+	return c.current().manifests, nil
+}
+
+func (c *Catalog) NewStore(packs []string, filter Filter, seed int64) (Questioner, error) {
+	current := c.current()
+	candidates, err := current.candidates(packs)
+	if err != nil {
+		return nil, err
+	}
+	store := newView(current.questions, candidates, filter, seed)
+	// the store is only weighed by the history when the room uses a group, so the seed alone decides the order
+	store.history = c.history
+	return store, nil
+}
+
+// snapshot is the packs of a catalog when they were loaded, it is never changed after it is created
+type snapshot struct {
+	manifests []models.PackManifest
+	// questions has the questions of all packs, a question in more than one pack is only in it once
+	questions []models.Question
+	// byPack has the index in questions of the questions in each pack
+	byPack map[string][]int
+}
+
+func newSnapshot(packs []Pack) *snapshot {
+	s := &snapshot{byPack: make(map[string][]int)}
+	index := make(map[string]int)
+	for _, p := range packs {
+		s.manifests = append(s.manifests, p.Manifest)
+		s.byPack[p.Manifest.Id] = []int{}
+		for _, q := range p.Questions {
+			i, seen := index[q.Id]
+			if !seen {
+				i = len(s.questions)
+				index[q.Id] = i
+				s.questions = append(s.questions, q)
+			}
+			s.byPack[p.Manifest.Id] = append(s.byPack[p.Manifest.Id], i)
+		}
+	}
+	return s
+}
+
+// candidates returns the index of the questions in the chosen packs, it is nil when all packs are chosen
+func (s *snapshot) candidates(chosen []string) ([]int, error) {
+	if len(chosen) == 0 {
+		return nil, nil
+	}
+	seen := make(map[int]bool)
+	candidates := []int{}
+	for _, id := range chosen {
+		indexes, ok := s.byPack[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown question pack '%s'", id)
+		}
+		for _, i := range indexes {
+			if !seen[i] {
+				seen[i] = true
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// validatePacks checks that there are enough questions to play a round, and that the question ids are unique within a pack
+func validatePacks(packs []Pack) error {
+	if len(packs) == 0 {
+		return errors.New("there are no question packs")
+	}
+	if n := len(merge(packs)); n < NumberOfQuestionsToFind {
+		return fmt.Errorf("there must be at least %d questions, found %d", NumberOfQuestionsToFind, n)
+	}
+	for _, p := range packs {
+		ids := make(map[string]bool)
+		for _, q := range p.Questions {
+			if ids[q.Id] {
+				return fmt.Errorf("question '%s' is in pack '%s' more than once", q.Id, p.Manifest.Id)
+			}
+			ids[q.Id] = true
+		}
+	}
+	return nil
+}
+
+// dirFingerprint describes the names, sizes and modification times of the files in dir, it changes when a file is changed
+func dirFingerprint(dir string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to read the packs in '%s': %w", dir, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,145 @@
+package question
+
+import (
+	"encoding/json"
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// copyTestPacks copies the test packs to a directory the test can change
+func copyTestPacks(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range []string{ManifestFileName, QuestionsFileName} {
+		b, err := os.ReadFile(filepath.Join(TestPacksDir, "test", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "test"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "test", name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeTestQuestions(t *testing.T, dir string, questions []models.Question) {
+	b, err := json.Marshal(models.Questions{Questions: questions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "test", QuestionsFileName)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the modification time must change even when the file is written within the same tick
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testQuestions(ids ...string) []models.Question {
+	var questions []models.Question
+	for _, id := range ids {
+		questions = append(questions, models.Question{Id: id, Question: models.QuestionTranslations{"en": id}})
+	}
+	return questions
+}
+
+func TestCatalogReload(t *testing.T) {
+	dir := copyTestPacks(t)
+	c, err := NewCatalog(dir, nil)
+	assert.NoError(t, err)
+	before, err := c.NewStore(nil, Filter{}, TestSeed)
+	assert.NoError(t, err)
+
+	writeTestQuestions(t, dir, testQuestions("a", "b", "c", "d"))
+	changed, err := c.changed()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, c.Reload())
+	changed, _ = c.changed()
+	assert.False(t, changed)
+
+	manifests, _ := c.Packs()
+	assert.Equal(t, 4, manifests[0].QuestionCount)
+	after, _ := c.NewStore(nil, Filter{}, TestSeed)
+	questions, err := after.GetFourUnique(nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, ids(questions))
+
+	questions, err = before.GetFourUnique(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, ids(questions), "a", "a store keeps the questions it was created with")
+}
+
+func TestCatalogReloadKeepsQuestionsWhenInvalid(t *testing.T) {
+	dir := copyTestPacks(t)
+	c, err := NewCatalog(dir, nil)
+	assert.NoError(t, err)
+	manifests, _ := c.Packs()
+	count := manifests[0].QuestionCount
+
+	writeTestQuestions(t, dir, testQuestions("a", "b"))
+	assert.Error(t, c.Reload(), "there are not enough questions for a round")
+
+	writeTestQuestions(t, dir, testQuestions("a", "a", "b", "c", "d"))
+	assert.Error(t, c.Reload(), "question ids must be unique")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "test", QuestionsFileName), []byte("{"), 0644))
+	assert.Error(t, c.Reload(), "the file must be valid json")
+
+	manifests, _ = c.Packs()
+	assert.Equal(t, count, manifests[0].QuestionCount)
+
+	_, err = NewCatalog(t.TempDir(), nil)
+	assert.Error(t, err)
+}
+
+func ids(questions []models.Question) []string {
+	var result []string
+	for _, q := range questions {
+		result = append(result, q.Id)
+	}
+	return result
+}
+
+func TestCatalogStoresShareTheQuestions(t *testing.T) {
+	dir := copyTestPacks(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "extra"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "extra", ManifestFileName), []byte(`{"name": "Extra"}`), 0644))
+	b, _ := json.Marshal(models.Questions{Questions: testQuestions("a", "b", "c", "d")})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "extra", QuestionsFileName), b, 0644))
+	c, err := NewCatalog(dir, nil)
+	assert.NoError(t, err)
+
+	first, _ := c.NewStore(nil, Filter{}, TestSeed)
+	second, _ := c.NewStore(nil, Filter{}, TestSeed)
+	assert.Same(t, &first.(*Store).questions[0], &second.(*Store).questions[0], "the stores do not copy the questions")
+
+	var used []string
+	for i := 0; i < 3; i++ {
+		questions, err := first.GetFourUnique(used)
+		assert.NoError(t, err)
+		used = append(used, ids(questions)...)
+	}
+	_, err = first.GetFourUnique(used)
+	assert.Error(t, err, "all the questions are used")
+	remaining, _ := second.Remaining(used[:4])
+	assert.Equal(t, 8, remaining, "each store has its own used ids")
+
+	extra, err := c.NewStore([]string{"extra"}, Filter{}, TestSeed)
+	assert.NoError(t, err)
+	questions, err := extra.GetFourUnique(nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, ids(questions))
+
+	_, err = c.NewStore([]string{"unknown"}, Filter{}, TestSeed)
+	assert.Error(t, err)
+}
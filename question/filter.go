@@ -0,0 +1,63 @@
+package question
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"strings"
+)
+
+// ratingLevels orders the content ratings, a question is allowed when its level is at most the level of the max rating
+var ratingLevels = map[models.ContentRating]int{
+	models.SafeRating:  0,
+	models.PartyRating: 1,
+	models.AdultRating: 2,
+}
+
+// Filter decides which questions a store serves, the zero value serves all questions
+type Filter struct {
+	// Language is the BCP 47 tag of the language to serve the questions in
+	Language string
+	// Categories are the allowed categories, all categories are allowed when it is empty
+	Categories []string
+	// MaxRating is the highest allowed content rating, all ratings are allowed when it is empty
+	MaxRating models.ContentRating
+}
+
+// NewFilter creates the filter for the room settings
+func NewFilter(settings models.RoomSettings) Filter {
+	return Filter{
+		Language:   settings.Language,
+		Categories: settings.Categories,
+		MaxRating:  settings.MaxRating,
+	}
+}
+
+// ValidateRating checks that the content rating is one of the known ratings
+func ValidateRating(rating models.ContentRating) error {
+	if _, ok := ratingLevels[rating]; !ok {
+		return fmt.Errorf("unknown content rating '%s'", rating)
+	}
+	return nil
+}
+
+// Allows returns true if the question has one of the allowed categories and is rated at most the max rating.
+// Questions without a rating are only allowed when all ratings are allowed.
+func (f Filter) Allows(q models.Question) bool {
+	if f.MaxRating != "" {
+		level, rated := ratingLevels[q.Rating]
+		if !rated || level > ratingLevels[f.MaxRating] {
+			return false
+		}
+	}
+	if len(f.Categories) == 0 {
+		return true
+	}
+	for _, category := range q.Categories {
+		for _, allowed := range f.Categories {
+			if strings.EqualFold(category, allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package question
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFilter_Allows(t *testing.T) {
+	drinking := models.Question{Categories: []string{"drinking", "party"}, Rating: models.PartyRating}
+	humor := models.Question{Categories: []string{"humor"}, Rating: models.SafeRating}
+	unrated := models.Question{Categories: []string{"humor"}}
+
+	var tests = []struct {
+		testName string
+		filter   Filter
+		question models.Question
+		expected bool
+	}{
+		{"the zero value allows all questions", Filter{}, drinking, true},
+		{"the zero value allows unrated questions", Filter{}, unrated, true},
+		{"rated above the max rating", Filter{MaxRating: models.SafeRating}, drinking, false},
+		{"rated below the max rating", Filter{MaxRating: models.AdultRating}, drinking, true},
+		{"unrated with a max rating", Filter{MaxRating: models.AdultRating}, unrated, false},
+		{"one of the categories is allowed", Filter{Categories: []string{"Party"}}, drinking, true},
+		{"no allowed categories", Filter{Categories: []string{"party"}}, humor, false},
+		{"category and rating", Filter{Categories: []string{"humor"}, MaxRating: models.SafeRating}, humor, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.Allows(tt.question))
+		})
+	}
+}
+
+func TestStore_GetFourUniqueFiltersQuestions(t *testing.T) {
+	s, err := NewPackStore("../packs", nil, Filter{MaxRating: models.SafeRating}, TestSeed)
+	assert.NoError(t, err)
+	var ids []string
+	for {
+		qs, err := s.GetFourUnique(ids)
+		if err != nil {
+			break
+		}
+		for _, q := range qs {
+			assert.Equal(t, models.SafeRating, q.Rating, q.Id)
+			ids = append(ids, q.Id)
+		}
+	}
+	assert.NotEmpty(t, ids)
+}
@@ -0,0 +1,225 @@
+package question
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// HistoryWindow is how long a question played by a group is served less often to the group
+	HistoryWindow = 30 * 24 * time.Hour
+	// SeenWeight is the weight of a question the group has just played, it grows back to 1 over the HistoryWindow
+	SeenWeight = 0.05
+)
+
+// History remembers the questions played by each group across games
+type History interface {
+	// Seen returns when the group last played each question
+	Seen(group string) (map[string]time.Time, error)
+	// Plays returns the number of times each question has been played by all groups
+	Plays() (map[string]int, error)
+	// Record remembers that the group played the questions, only the plays are counted when group is empty
+	Record(group string, ids []string, at time.Time) error
+}
+
+// Grouper is a store which uses a History to serve the questions a group has not played recently
+type Grouper interface {
+	// UseGroup serves the questions the group has not played recently before the questions it has played
+	UseGroup(group string) error
+	// Played remembers that the questions were played, by the group of the store when it has one
+	Played(ids []string) error
+}
+
+// weight is the chance of a question being picked compared to the other questions.
+// Questions played less by all groups are fresher, and questions the group played recently are served less often.
+func weight(plays int, seen, now time.Time) float64 {
+	w := 1 / (1 + math.Log1p(float64(plays)))
+	if !seen.IsZero() {
+		if age := now.Sub(seen); age < HistoryWindow {
+			w *= SeenWeight + (1-SeenWeight)*float64(age)/float64(HistoryWindow)
+		}
+	}
+	return w
+}
+
+// weights returns the weight of each question id for the group, the group can be empty
+func weights(history History, group string, now time.Time) (func(id string) float64, error) {
+	plays, err := history.Plays()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]time.Time{}
+	if group != "" {
+		if seen, err = history.Seen(group); err != nil {
+			return nil, err
+		}
+	}
+	return func(id string) float64 { return weight(plays[id], seen[id], now) }, nil
+}
+
+// weightedShuffle orders the items randomly, an item is placed earlier with a chance given by its weight.
+// Each item gets the key u^(1/w) for a random u, and the items are sorted by the key.
+func weightedShuffle(items []int, weight func(item int) float64, r *rand.Rand) {
+	keys := make(map[int]float64, len(items))
+	for _, item := range items {
+		// log(u)/w orders the items the same as u^(1/w) without losing precision for small weights
+		keys[item] = math.Log(1-r.Float64()) / weight(item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return keys[items[i]] > keys[items[j]] })
+}
+
+// MemoryHistory is a History which is kept in memory, it is forgotten when the server restarts
+type MemoryHistory struct {
+	mu    sync.Mutex
+	seen  map[string]map[string]time.Time
+	plays map[string]int
+	// expired is when the groups were last checked for questions played before the window
+	expired time.Time
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{seen: make(map[string]map[string]time.Time), plays: make(map[string]int)}
+}
+
+func (h *MemoryHistory) Seen(group string) (map[string]time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	seen := make(map[string]time.Time, len(h.seen[group]))
+	for id, at := range h.seen[group] {
+		seen[id] = at
+	}
+	return seen, nil
+}
+
+func (h *MemoryHistory) Plays() (map[string]int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	plays := make(map[string]int, len(h.plays))
+	for id, n := range h.plays {
+		plays[id] = n
+	}
+	return plays, nil
+}
+
+func (h *MemoryHistory) Record(group string, ids []string, at time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range ids {
+		h.plays[id]++
+	}
+	h.expire(at)
+	if group == "" {
+		return nil
+	}
+	seen, ok := h.seen[group]
+	if !ok {
+		seen = make(map[string]time.Time)
+		h.seen[group] = seen
+	}
+	for id, playedAt := range seen {
+		// questions played before the window have their full weight again
+		if at.Sub(playedAt) >= HistoryWindow {
+			delete(seen, id)
+		}
+	}
+	for _, id := range ids {
+		seen[id] = at
+	}
+	return nil
+}
+
+// expire forgets the questions played before the window in every group, and the groups which have not
+// played since. Groups of players are rarely seen again, so they would otherwise be kept forever.
+// All the groups are only checked once per window, h.mu must be held
+func (h *MemoryHistory) expire(now time.Time) {
+	if now.Sub(h.expired) < HistoryWindow {
+		return
+	}
+	h.expired = now
+	for group, seen := range h.seen {
+		for id, playedAt := range seen {
+			if now.Sub(playedAt) >= HistoryWindow {
+				delete(seen, id)
+			}
+		}
+		if len(seen) == 0 {
+			delete(h.seen, group)
+		}
+	}
+}
+
+// SQLiteHistory is a History in the questions database, it is kept when the server restarts
+type SQLiteHistory struct {
+	DB *sql.DB
+}
+
+func (h SQLiteHistory) Seen(group string) (map[string]time.Time, error) {
+	rows, err := h.DB.Query(`SELECT question_id, seen_at FROM question_history WHERE group_key = ?`, group)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the history of '%s': %w", group, err)
+	}
+	defer rows.Close()
+	seen := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var at int64
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		seen[id] = time.Unix(at, 0)
+	}
+	return seen, rows.Err()
+}
+
+func (h SQLiteHistory) Plays() (map[string]int, error) {
+	rows, err := h.DB.Query(`SELECT question_id, plays FROM question_plays`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the plays: %w", err)
+	}
+	defer rows.Close()
+	plays := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		plays[id] = n
+	}
+	return plays, rows.Err()
+}
+
+func (h SQLiteHistory) Record(group string, ids []string, at time.Time) error {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.Exec(`
+			INSERT INTO question_plays (question_id, plays) VALUES (?, 1)
+			ON CONFLICT (question_id) DO UPDATE SET plays = plays + 1`, id); err != nil {
+			return fmt.Errorf("unable to count the play of '%s': %w", id, err)
+		}
+		if group == "" {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO question_history (group_key, question_id, seen_at) VALUES (?, ?, ?)
+			ON CONFLICT (group_key, question_id) DO UPDATE SET seen_at = excluded.seen_at`,
+			group, id, at.Unix()); err != nil {
+			return fmt.Errorf("unable to record '%s' for '%s': %w", id, group, err)
+		}
+	}
+	// questions played before the window have their full weight again, they are deleted for every group
+	// so the groups which stopped playing are forgotten as well
+	if _, err := tx.Exec(`DELETE FROM question_history WHERE seen_at < ?`, at.Add(-HistoryWindow).Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
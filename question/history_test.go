@@ -0,0 +1,107 @@
+package question
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestWeight(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, 1.0, weight(0, time.Time{}, now))
+	assert.Less(t, weight(10, time.Time{}, now), weight(1, time.Time{}, now), "questions played less are fresher")
+	assert.InDelta(t, SeenWeight, weight(0, now, now), 0.001, "a question played just now is rarely served")
+	assert.Less(t, weight(0, now.Add(-time.Hour), now), weight(0, now.Add(-HistoryWindow/2), now))
+	assert.Equal(t, 1.0, weight(0, now.Add(-HistoryWindow), now), "questions played before the window have their full weight")
+}
+
+func TestWeightedShuffle(t *testing.T) {
+	r := rand.New(rand.NewSource(TestSeed))
+	var heavyFirst int
+	for i := 0; i < 100; i++ {
+		items := []int{0, 1, 2, 3}
+		weightedShuffle(items, func(item int) float64 {
+			if item == 3 {
+				return 100
+			}
+			return 1
+		}, r)
+		assert.ElementsMatch(t, []int{0, 1, 2, 3}, items)
+		if items[0] == 3 {
+			heavyFirst++
+		}
+	}
+	assert.Greater(t, heavyFirst, 90)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	h := NewMemoryHistory()
+	now := time.Now()
+	assert.NoError(t, h.Record("group", []string{"a", "b"}, now.Add(-HistoryWindow)))
+	assert.NoError(t, h.Record("", []string{"a"}, now))
+	assert.NoError(t, h.Record("group", []string{"c"}, now))
+
+	plays, _ := h.Plays()
+	assert.Equal(t, map[string]int{"a": 2, "b": 1, "c": 1}, plays)
+	seen, _ := h.Seen("group")
+	assert.Equal(t, map[string]time.Time{"c": now}, seen, "questions played before the window are forgotten")
+	seen, _ = h.Seen("other")
+	assert.Empty(t, seen)
+
+	assert.NoError(t, h.Record("players:a,b", []string{"a"}, now))
+	assert.Equal(t, now, h.expired, "the groups are only checked once per window")
+	assert.NoError(t, h.Record("players:c,d", []string{"a"}, now.Add(HistoryWindow)))
+	assert.NotContains(t, h.seen, "players:a,b", "groups which have not played within the window are forgotten")
+	assert.NotContains(t, h.seen, "group")
+	assert.Contains(t, h.seen, "players:c,d")
+}
+
+func TestStoreServesQuestionsTheGroupHasNotPlayed(t *testing.T) {
+	h := NewMemoryHistory()
+	c, err := NewCatalog(TestPacksDir, h)
+	assert.NoError(t, err)
+	played, _ := c.NewStore(nil, Filter{}, TestSeed)
+	first, err := played.GetFourUnique(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, played.(Grouper).UseGroup("group"))
+	assert.NoError(t, played.(Grouper).Played(getQuestionIds(first)))
+
+	var replayed int
+	for seed := int64(0); seed < 50; seed++ {
+		store, _ := c.NewStore(nil, Filter{}, seed)
+		assert.NoError(t, store.(Grouper).UseGroup("group"))
+		questions, err := store.GetFourUnique(nil)
+		assert.NoError(t, err)
+		for _, q := range questions {
+			if contains(getQuestionIds(first), q.Id) {
+				replayed++
+			}
+		}
+	}
+	assert.Less(t, replayed, 25, "questions the group played are rarely served again")
+}
+
+func TestStoreWithoutGroupIsNotWeighed(t *testing.T) {
+	h := NewMemoryHistory()
+	c, err := NewCatalog(TestPacksDir, h)
+	assert.NoError(t, err)
+	questions := func() []string {
+		store, _ := c.NewStore(nil, Filter{}, TestSeed)
+		q, err := store.GetFourUnique(nil)
+		assert.NoError(t, err)
+		return getQuestionIds(q)
+	}
+	before := questions()
+	assert.NoError(t, h.Record("group", before, time.Now()))
+	assert.Equal(t, before, questions(), "the plays of other games do not change the order of the seed")
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
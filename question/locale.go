@@ -0,0 +1,76 @@
+package question
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"strings"
+)
+
+// DefaultLanguage is used when a question does not have a translation in the language of the room
+const DefaultLanguage = "en"
+
+// languageAliases are languages where the questions are written with another tag,
+// Norwegian Bokmål and Nynorsk questions are written as Norwegian
+var languageAliases = map[string]string{
+	"nb": "no",
+	"nn": "no",
+}
+
+// ValidateLanguage checks that the language is a well formed BCP 47 tag, like 'en', 'no' or 'nb-NO'
+func ValidateLanguage(language string) error {
+	subtags := strings.Split(language, "-")
+	if l := len(subtags[0]); l < 2 || l > 3 || !isLetters(subtags[0]) {
+		return fmt.Errorf("'%s' is not a language tag, it must start with a two or three letter language", language)
+	}
+	for _, s := range subtags[1:] {
+		if len(s) < 1 || len(s) > 8 || !isAlphanumeric(s) {
+			return fmt.Errorf("'%s' is not a language tag, '%s' is not a valid subtag", language, s)
+		}
+	}
+	return nil
+}
+
+// FallbackChain returns the languages to look for in order, the most specific tag is first.
+// The chain of 'nb-NO' is 'nb-NO', 'nb' and 'no'. The DefaultLanguage is not part of the chain.
+func FallbackChain(language string) []string {
+	var chain []string
+	subtags := strings.Split(language, "-")
+	for i := len(subtags); i > 0; i-- {
+		chain = append(chain, strings.Join(subtags[:i], "-"))
+	}
+	if alias, ok := languageAliases[strings.ToLower(subtags[0])]; ok {
+		chain = append(chain, alias)
+	}
+	return chain
+}
+
+// Translate finds the translation for the first language in the chain, an empty translation is treated as missing.
+// It returns the translation and the language of the translation.
+func Translate(t models.QuestionTranslations, chain []string) (string, string, bool) {
+	for _, language := range chain {
+		for tag, text := range t {
+			if strings.EqualFold(tag, language) && text != "" {
+				return text, tag, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && !isLetters(string(r)) {
+			return false
+		}
+	}
+	return true
+}
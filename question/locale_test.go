@@ -0,0 +1,38 @@
+package question
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateLanguage(t *testing.T) {
+	for _, valid := range []string{"en", "no", "nb-NO", "zh-Hant-TW", "es-419"} {
+		assert.NoError(t, ValidateLanguage(valid), valid)
+	}
+	for _, invalid := range []string{"", "e", "english", "en-", "en_US", "12"} {
+		assert.Error(t, ValidateLanguage(invalid), invalid)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	assert.Equal(t, []string{"en"}, FallbackChain("en"))
+	assert.Equal(t, []string{"nb-NO", "nb", "no"}, FallbackChain("nb-NO"))
+	assert.Equal(t, []string{"zh-Hant-TW", "zh-Hant", "zh"}, FallbackChain("zh-Hant-TW"))
+}
+
+func TestTranslate(t *testing.T) {
+	translations := models.QuestionTranslations{"no": "spørsmål", "en": "", "en-GB": "question"}
+
+	text, language, ok := Translate(translations, FallbackChain("nb-NO"))
+	assert.True(t, ok)
+	assert.Equal(t, "spørsmål", text)
+	assert.Equal(t, "no", language)
+
+	_, _, ok = Translate(translations, FallbackChain("en"))
+	assert.False(t, ok, "an empty translation is missing")
+
+	text, _, ok = Translate(translations, FallbackChain("EN-gb"))
+	assert.True(t, ok, "language tags are not case sensitive")
+	assert.Equal(t, "question", text)
+}
@@ -0,0 +1,138 @@
+package question
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"os"
+	"path/filepath"
+)
+
+const (
+	ManifestFileName  = "manifest.json"
+	QuestionsFileName = "questions.json"
+)
+
+// Pack is a named set of questions, each pack is a directory with a manifest and a questions file
+type Pack struct {
+	Manifest  models.PackManifest
+	Questions []models.Question
+}
+
+// LoadPacks loads every directory in dir which has a manifest, the packs are ordered by id
+func LoadPacks(dir string) ([]Pack, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the packs in '%s': %w", dir, err)
+	}
+	var packs []Pack
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		manifestPath := filepath.Join(dir, e.Name(), ManifestFileName)
+		if _, err := os.Stat(manifestPath); err != nil {
+			continue
+		}
+		pack, err := loadPack(filepath.Join(dir, e.Name()), manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		pack.Manifest.Id = e.Name()
+		packs = append(packs, pack)
+	}
+	return packs, nil
+}
+
+func loadPack(dir, manifestPath string) (Pack, error) {
+	var pack Pack
+	b, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return pack, fmt.Errorf("unable to read manifest '%s': %w", manifestPath, err)
+	}
+	if err := json.Unmarshal(b, &pack.Manifest); err != nil {
+		return pack, fmt.Errorf("unable to unmarshal manifest '%s': %w", manifestPath, err)
+	}
+	questions, err := readQuestions(filepath.Join(dir, QuestionsFileName))
+	if err != nil {
+		return pack, err
+	}
+	pack.Questions = questions.Questions
+	pack.Manifest.QuestionCount = len(questions.Questions)
+	return pack, nil
+}
+
+// ListPacks returns the manifests of the packs in dir
+func ListPacks(dir string) ([]models.PackManifest, error) {
+	packs, err := LoadPacks(dir)
+	if err != nil {
+		return nil, err
+	}
+	var manifests []models.PackManifest
+	for _, p := range packs {
+		manifests = append(manifests, p.Manifest)
+	}
+	return manifests, nil
+}
+
+// NewPackStore creates a store with the questions of the chosen packs in dir, all packs are used when none are chosen.
+// Questions which are in more than one pack are only served once.
+func NewPackStore(dir string, chosen []string, filter Filter, seed int64) (*Store, error) {
+	packs, err := LoadPacks(dir)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectPacks(packs, chosen)
+	if err != nil {
+		return nil, err
+	}
+	return newStore(merge(selected), filter, seed), nil
+}
+
+// PackDir is a Source with the packs in a directory, the packs are read each time a store is created
+type PackDir struct {
+	Dir string
+}
+
+func (d PackDir) Packs() ([]models.PackManifest, error) {
+	return ListPacks(d.Dir)
+}
+
+func (d PackDir) NewStore(packs []string, filter Filter, seed int64) (Questioner, error) {
+	return NewPackStore(d.Dir, packs, filter, seed)
+}
+
+func selectPacks(packs []Pack, chosen []string) ([]Pack, error) {
+	if len(chosen) == 0 {
+		return packs, nil
+	}
+	byId := make(map[string]Pack)
+	for _, p := range packs {
+		byId[p.Manifest.Id] = p
+	}
+	var selected []Pack
+	for _, id := range chosen {
+		p, ok := byId[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown question pack '%s'", id)
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// merge returns the questions of the packs, a question in more than one pack is taken from the first pack
+func merge(packs []Pack) []models.Question {
+	seen := make(map[string]bool)
+	var questions []models.Question
+	for _, p := range packs {
+		for _, q := range p.Questions {
+			if seen[q.Id] {
+				continue
+			}
+			seen[q.Id] = true
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
@@ -0,0 +1,40 @@
+package question
+
+import (
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const TestPacksDir = "../testPacks"
+
+func TestLoadPacks(t *testing.T) {
+	packs, err := LoadPacks(TestPacksDir)
+	assert.NoError(t, err)
+	assert.Len(t, packs, 1)
+	assert.Equal(t, "test", packs[0].Manifest.Id)
+	assert.Equal(t, "Test", packs[0].Manifest.Name)
+	assert.Equal(t, len(packs[0].Questions), packs[0].Manifest.QuestionCount)
+
+	_, err = LoadPacks("./does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestNewPackStore(t *testing.T) {
+	_, err := NewPackStore(TestPacksDir, []string{"test"}, Filter{}, TestSeed)
+	assert.NoError(t, err)
+
+	_, err = NewPackStore(TestPacksDir, []string{"unknown"}, Filter{}, TestSeed)
+	assert.Error(t, err)
+	assert.Error(t, ValidatePacks(PackDir{Dir: TestPacksDir}, []string{"test", "unknown"}))
+	assert.NoError(t, ValidatePacks(PackDir{Dir: TestPacksDir}, nil))
+}
+
+func TestMerge(t *testing.T) {
+	first := Pack{Questions: []models.Question{{Id: "1", Question: models.QuestionTranslations{"en": "first"}}, {Id: "2"}}}
+	second := Pack{Questions: []models.Question{{Id: "1", Question: models.QuestionTranslations{"en": "second"}}, {Id: "3"}}}
+
+	merged := merge([]Pack{first, second})
+	assert.Len(t, merged, 3, "questions in more than one pack are only served once")
+	assert.Equal(t, "first", merged[0].Question["en"])
+}
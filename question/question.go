@@ -2,9 +2,9 @@ package question
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/akselleirv/introspect/models"
-	"io"
 	"log"
 	"math/rand"
 	"os"
@@ -19,30 +19,151 @@ type Questioner interface {
 	// GetFourUnique gets a slice of question ids of the question that already have been received
 	// then it returns a slice of four new questions
 	GetFourUnique(usedIds []string) ([]models.Question, error)
+	// Remaining returns the number of questions the store can serve which are not in usedIds
+	Remaining(usedIds []string) (int, error)
 }
 
+// Store serves the questions of one room. The questions are shared with the other stores and never changed,
+// a store only has its own order of the questions. The used ids are passed by the room on each call,
+// so a store can be read by several goroutines at once.
 type Store struct {
-	q models.Questions
+	questions []models.Question
+	// order has the index in questions of the questions the store serves, in the order they are served
+	order  []int
+	filter Filter
+	chain  []string
+	rand   *rand.Rand
+	// history is used to weigh the questions, the questions are equally likely when it is nil
+	history History
+	group   string
 }
 
-func NewStore(filePath string) *Store {
-	s := Store{q: load(filePath)}
+// NewStore loads the questions in the file, only the questions allowed by the filter are served.
+// The questions are shuffled with the seed.
+func NewStore(filePath string, filter Filter, seed int64) *Store {
+	return newStore(load(filePath).Questions, filter, seed)
+}
+
+func newStore(questions []models.Question, filter Filter, seed int64) *Store {
+	return newView(questions, nil, filter, seed)
+}
 
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(s.q.Questions),
+// newView creates a store which serves the candidates, the indexes of questions, in the order given by the seed.
+// All the questions are candidates when candidates is nil.
+func newView(questions []models.Question, candidates []int, filter Filter, seed int64) *Store {
+	s := Store{questions: questions, filter: filter, rand: rand.New(rand.NewSource(seed))}
+	if filter.Language != "" {
+		s.chain = FallbackChain(filter.Language)
+	}
+	if candidates == nil {
+		candidates = make([]int, len(questions))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	}
+	for _, i := range candidates {
+		if filter.Allows(questions[i]) {
+			s.order = append(s.order, i)
+		}
+	}
+
+	s.rand.Shuffle(len(s.order),
 		func(i, j int) {
-			s.q.Questions[i], s.q.Questions[j] = s.q.Questions[j], s.q.Questions[i]
+			s.order[i], s.order[j] = s.order[j], s.order[i]
 		})
+	if filter.Language != "" {
+		s.order = s.byLanguage()
+	}
 
 	return &s
 }
 
+// UseGroup serves the questions the group has not played recently first, it has no effect without a history
+func (s *Store) UseGroup(group string) error {
+	s.group = group
+	return s.weigh()
+}
+
+func (s *Store) Played(ids []string) error {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.Record(s.group, ids, time.Now())
+}
+
+// weigh orders the questions by the weights from the history of the group.
+// Stores without a group are not weighed, so their order only depends on the seed.
+func (s *Store) weigh() error {
+	if s.history == nil || s.group == "" {
+		return nil
+	}
+	weigh, err := weights(s.history, s.group, time.Now())
+	if err != nil {
+		return err
+	}
+	weightedShuffle(s.order, func(i int) float64 { return weigh(s.questions[i].Id) }, s.rand)
+	if s.chain != nil {
+		s.order = s.byLanguage()
+	}
+	return nil
+}
+
+// byLanguage puts the questions translated to the language of the store before the questions
+// which fall back to the DefaultLanguage, questions without either are not served
+func (s *Store) byLanguage() []int {
+	var translated, fallback []int
+	for _, i := range s.order {
+		if _, _, ok := Translate(s.questions[i].Question, s.chain); ok {
+			translated = append(translated, i)
+		} else if _, _, ok := Translate(s.questions[i].Question, []string{DefaultLanguage}); ok {
+			fallback = append(fallback, i)
+		}
+	}
+	return append(translated, fallback...)
+}
+
+// forLanguage sets the text of the questions to the translation in the language.
+// The questions translated to the language are served before the questions which fall back to
+// the DefaultLanguage, and questions without either are not served.
+func forLanguage(qs []models.Question, language string) []models.Question {
+	chain := FallbackChain(language)
+	var translated, fallback []models.Question
+	for _, q := range qs {
+		if text, tag, ok := Translate(q.Question, chain); ok {
+			q.Text, q.Language = text, tag
+			translated = append(translated, q)
+		} else if text, tag, ok := Translate(q.Question, []string{DefaultLanguage}); ok {
+			q.Text, q.Language = text, tag
+			fallback = append(fallback, q)
+		}
+	}
+	return append(translated, fallback...)
+}
+
+// inLanguage returns the question with the text set to the first translation in the chain, or to the DefaultLanguage
+func inLanguage(q models.Question, chain []string) models.Question {
+	text, tag, ok := Translate(q.Question, chain)
+	if !ok {
+		text, tag, _ = Translate(q.Question, []string{DefaultLanguage})
+	}
+	q.Text, q.Language = text, tag
+	return q
+}
+
+// GetFourUnique returns four questions which are not used. The returned questions are not marked as used
+// since the room may not play all of them, and a room recycling questions passes fewer used ids.
 func (s *Store) GetFourUnique(usedIds []string) ([]models.Question, error) {
+	used := idSet(usedIds)
 	var result []models.Question
-	for _, q := range s.q.Questions {
-		if isNew(usedIds, q.Id) {
-			result = append(result, q)
+	for _, i := range s.order {
+		q := s.questions[i]
+		if used[q.Id] {
+			continue
 		}
+		if s.chain != nil {
+			q = inLanguage(q, s.chain)
+		}
+		result = append(result, q)
 		if len(result) == NumberOfQuestionsToFind {
 			break
 		}
@@ -53,36 +174,97 @@ func (s *Store) GetFourUnique(usedIds []string) ([]models.Question, error) {
 	return result, nil
 }
 
-// isNew checks if the question id is new / has been used
-func isNew(usedIds []string, questionId string) bool {
-	for _, id := range usedIds {
-		if id == questionId {
-			return false
+func (s *Store) Remaining(usedIds []string) (int, error) {
+	used := idSet(usedIds)
+	var remaining int
+	for _, i := range s.order {
+		if !used[s.questions[i].Id] {
+			remaining++
 		}
 	}
-	return true
+	return remaining, nil
+}
+
+// idSet returns the ids as a set
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
 }
 
 // load loads the questions and set them in the Store struct
 func load(filePath string) models.Questions {
-	f, err := os.Open(filePath)
+	questions, err := readQuestions(filePath)
 	if err != nil {
-		pwd, err := os.Getwd()
-		if err != nil {
-			log.Fatalln("unable to get current dir: ", err)
-		}
+		pwd, _ := os.Getwd()
 		log.Println("current dir is: ", pwd)
-		log.Fatalln("unable to open 'question.json")
-	}
-	fr, err := io.ReadAll(f)
-	if err != nil {
-		log.Fatalln("unable to read file: ", err)
+		log.Fatalln(err)
 	}
+	return questions
+}
 
+// readQuestions reads the questions in the file, questions which can not be played are skipped
+func readQuestions(filePath string) (models.Questions, error) {
 	var questions models.Questions
-	err = json.Unmarshal(fr, &questions)
+	fr, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Println("unable to unmarshal file: ", err)
+		return questions, fmt.Errorf("unable to read '%s': %w", filePath, err)
 	}
-	return questions
+	if err := json.Unmarshal(fr, &questions); err != nil {
+		return questions, fmt.Errorf("unable to unmarshal '%s': %w", filePath, err)
+	}
+
+	var valid []models.Question
+	for _, q := range questions.Questions {
+		if err := validate(q); err != nil {
+			log.Printf("skipping question '%s': %s", q.Id, err)
+			continue
+		}
+		valid = append(valid, q)
+	}
+	questions.Questions = valid
+	return questions, nil
+}
+
+// validate checks that the question has what its type needs to be played
+func validate(q models.Question) error {
+	if !hasTranslation(q.Question) {
+		return errors.New("the question has no translations")
+	}
+	if q.Rating != "" {
+		if err := ValidateRating(q.Rating); err != nil {
+			return err
+		}
+	}
+	if q.VotesPerPlayer < 0 {
+		return fmt.Errorf("votes per player can not be negative, got %d", q.VotesPerPlayer)
+	}
+	if q.VotesPerPlayer > 0 && q.Type != "" && q.Type != models.MostLikelyQuestion {
+		return fmt.Errorf("votes per player is only used by most likely questions")
+	}
+	switch q.Type {
+	case "", models.MostLikelyQuestion, models.RankingQuestion:
+	case models.RatingQuestion:
+		if q.Scale < 0 || q.Scale == 1 {
+			return fmt.Errorf("a rating question needs a scale of at least 2, got %d", q.Scale)
+		}
+	case models.ThisOrThatQuestion:
+		if len(q.Options) != 2 {
+			return fmt.Errorf("a this or that question needs two options, got %d", len(q.Options))
+		}
+	default:
+		return fmt.Errorf("unknown question type '%s'", q.Type)
+	}
+	return nil
+}
+
+func hasTranslation(t models.QuestionTranslations) bool {
+	for _, text := range t {
+		if text != "" {
+			return true
+		}
+	}
+	return false
 }
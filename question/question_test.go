@@ -8,7 +8,8 @@ import (
 
 const (
 	LengthUIID   = 36
-	TestFilePath = "../testQuestions.json"
+	TestFilePath = "../testPacks/test/questions.json"
+	TestSeed     = 1
 )
 
 func TestLoadSuccess(t *testing.T) {
@@ -22,7 +23,7 @@ func TestLoadSuccess(t *testing.T) {
 }
 
 func TestStore_GetFourUniqueSuccess(t *testing.T) {
-	s := NewStore(TestFilePath)
+	s := NewStore(TestFilePath, Filter{}, TestSeed)
 	q1s, err := s.GetFourUnique([]string{})
 
 	assert.NoError(t, err)
@@ -40,7 +41,7 @@ func TestStore_GetFourUniqueSuccess(t *testing.T) {
 }
 
 func TestStore_GetFourUniqueNoMoreQuestions(t *testing.T) {
-	s := NewStore(TestFilePath)
+	s := NewStore(TestFilePath, Filter{}, TestSeed)
 	var ids []string
 	var err error
 	var qs []models.Question
@@ -51,13 +52,26 @@ func TestStore_GetFourUniqueNoMoreQuestions(t *testing.T) {
 		ids = append(ids, getQuestionIds(qs)...)
 	}
 
-	// now we have requested all the question in the testQuestions.json
+	// now we have requested all the question in the test pack
 	assert.Len(t, ids, 2*NumberOfQuestionsToFind)
 	qs, err = s.GetFourUnique(ids)
 	assert.Nil(t, qs)
 	assert.EqualError(t, err, "unable to find 4 questions, found 0", "we requested the same ids we should got, it should result in an error")
 }
 
+func TestStoreSeed(t *testing.T) {
+	questions := func(seed int64) []string {
+		s := NewStore(TestFilePath, Filter{}, seed)
+		first, err := s.GetFourUnique(nil)
+		assert.NoError(t, err)
+		second, err := s.GetFourUnique(getQuestionIds(first))
+		assert.NoError(t, err)
+		return append(getQuestionIds(first), getQuestionIds(second)...)
+	}
+	assert.Equal(t, questions(TestSeed), questions(TestSeed), "stores with the same seed serve the same questions")
+	assert.NotEqual(t, questions(TestSeed), questions(TestSeed+1))
+}
+
 func getQuestionIds(qs []models.Question) []string {
 	var ids []string
 	for _, q := range qs {
@@ -65,3 +79,74 @@ func getQuestionIds(qs []models.Question) []string {
 	}
 	return ids
 }
+
+func TestValidate(t *testing.T) {
+	twoOptions := []models.QuestionTranslations{{"en": "Cats"}, {"en": "Dogs"}}
+	var tests = []struct {
+		testName  string
+		question  models.Question
+		expectErr bool
+	}{
+		{"questions without a type are most likely questions", models.Question{}, false},
+		{"ranking", models.Question{Type: models.RankingQuestion}, false},
+		{"rating with the default scale", models.Question{Type: models.RatingQuestion}, false},
+		{"rating with a scale of one", models.Question{Type: models.RatingQuestion, Scale: 1}, true},
+		{"this or that", models.Question{Type: models.ThisOrThatQuestion, Options: twoOptions}, false},
+		{"this or that without options", models.Question{Type: models.ThisOrThatQuestion}, true},
+		{"unknown type", models.Question{Type: "ESSAY"}, true},
+		{"no translations", models.Question{Question: models.QuestionTranslations{"en": ""}}, true},
+		{"most likely with three votes", models.Question{Type: models.MostLikelyQuestion, VotesPerPlayer: 3}, false},
+		{"votes per player in a ranking", models.Question{Type: models.RankingQuestion, VotesPerPlayer: 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if tt.question.Question == nil {
+				tt.question.Question = models.QuestionTranslations{"en": "question"}
+			}
+			err := validate(tt.question)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadQuestionsFile(t *testing.T) {
+	packs, err := LoadPacks("../packs")
+	assert.NoError(t, err)
+	for _, p := range packs {
+		assert.NotEmpty(t, p.Questions, p.Manifest.Id)
+		for _, question := range p.Questions {
+			assert.NotEmpty(t, question.Type, question.Id)
+			assert.NotEmpty(t, question.Rating, question.Id)
+		}
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	qs := []models.Question{
+		{Id: "english", Question: models.QuestionTranslations{"no": "", "en": "question"}},
+		{Id: "norwegian", Question: models.QuestionTranslations{"no": "spørsmål", "en": ""}},
+		{Id: "swedish", Question: models.QuestionTranslations{"sv": "fråga"}},
+	}
+
+	result := forLanguage(qs, "nb")
+	assert.Len(t, result, 2, "questions without the language or the default language are not served")
+	assert.Equal(t, "norwegian", result[0].Id, "questions in the language are served first")
+	assert.Equal(t, "spørsmål", result[0].Text)
+	assert.Equal(t, "english", result[1].Id)
+	assert.Equal(t, DefaultLanguage, result[1].Language)
+}
+
+func TestStore_Remaining(t *testing.T) {
+	s := NewStore(TestFilePath, Filter{}, TestSeed)
+	remaining, err := s.Remaining(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*NumberOfQuestionsToFind, remaining)
+
+	questions, _ := s.GetFourUnique(nil)
+	remaining, _ = s.Remaining(append(getQuestionIds(questions), "not-in-the-store"))
+	assert.Equal(t, NumberOfQuestionsToFind, remaining)
+}
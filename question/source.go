@@ -0,0 +1,34 @@
+package question
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+)
+
+// Source has the question packs, and creates the question store of a room
+type Source interface {
+	// Packs returns the manifests of the packs a room can choose from
+	Packs() ([]models.PackManifest, error)
+	// NewStore creates a store with the questions of the chosen packs, all packs are used when none are chosen.
+	// Stores created with the same seed from the same questions serve the questions in the same order,
+	// until a Grouper store is given a group which weighs the questions by the history.
+	NewStore(packs []string, filter Filter, seed int64) (Questioner, error)
+}
+
+// ValidatePacks returns an error if one of the chosen packs is not in the source
+func ValidatePacks(source Source, chosen []string) error {
+	packs, err := source.Packs()
+	if err != nil {
+		return err
+	}
+	exist := make(map[string]bool)
+	for _, p := range packs {
+		exist[p.Id] = true
+	}
+	for _, id := range chosen {
+		if !exist[id] {
+			return fmt.Errorf("unknown question pack '%s'", id)
+		}
+	}
+	return nil
+}
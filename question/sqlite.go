@@ -0,0 +1,390 @@
+package question
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/akselleirv/introspect/models"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	// the pure Go driver lets the server build without cgo
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order, a migration must never be changed after it has been released
+var migrations = []string{
+	`CREATE TABLE packs (
+		id          TEXT PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		languages   TEXT NOT NULL DEFAULT '[]',
+		author      TEXT NOT NULL DEFAULT '',
+		rating      TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE questions (
+		id     TEXT PRIMARY KEY,
+		pack   TEXT NOT NULL REFERENCES packs (id),
+		type   TEXT NOT NULL DEFAULT '',
+		rating TEXT NOT NULL DEFAULT '',
+		data   TEXT NOT NULL
+	);
+	CREATE INDEX questions_pack ON questions (pack);
+	CREATE TABLE question_translations (
+		question_id TEXT NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+		language    TEXT NOT NULL COLLATE NOCASE,
+		text        TEXT NOT NULL,
+		PRIMARY KEY (question_id, language)
+	);
+	CREATE TABLE question_categories (
+		question_id TEXT NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+		category    TEXT NOT NULL COLLATE NOCASE,
+		PRIMARY KEY (question_id, category)
+	);`,
+	`CREATE TABLE question_plays (
+		question_id TEXT PRIMARY KEY,
+		plays       INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE question_history (
+		group_key   TEXT NOT NULL,
+		question_id TEXT NOT NULL,
+		seen_at     INTEGER NOT NULL,
+		PRIMARY KEY (group_key, question_id)
+	);`,
+}
+
+// OpenSQLite opens the database in the file and applies the migrations which are missing
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open '%s': %w", path, err)
+	}
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applies the migrations which have not been applied to the database
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("unable to create the migrations table: %w", err)
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("unable to find the schema version: %w", err)
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to apply migration %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLiteSource is a Source with the packs in a SQLite database,
+// questions added to the database are served without restarting the server
+type SQLiteSource struct {
+	DB *sql.DB
+}
+
+func (s SQLiteSource) Packs() ([]models.PackManifest, error) {
+	rows, err := s.DB.Query(`
+		SELECT p.id, p.name, p.description, p.languages, p.author, p.rating, COUNT(q.id)
+		FROM packs p LEFT JOIN questions q ON q.pack = p.id
+		GROUP BY p.id ORDER BY p.id`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the packs: %w", err)
+	}
+	defer rows.Close()
+	var manifests []models.PackManifest
+	for rows.Next() {
+		var m models.PackManifest
+		var languages string
+		if err := rows.Scan(&m.Id, &m.Name, &m.Description, &languages, &m.Author, &m.Rating, &m.QuestionCount); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(languages), &m.Languages); err != nil {
+			return nil, fmt.Errorf("unable to read the languages of pack '%s': %w", m.Id, err)
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, rows.Err()
+}
+
+func (s SQLiteSource) NewStore(packs []string, filter Filter, seed int64) (Questioner, error) {
+	if err := ValidatePacks(s, packs); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: s.DB, packs: packs, filter: filter, rand: rand.New(rand.NewSource(seed))}, nil
+}
+
+// SQLiteStore selects the questions from the database each time, so new questions are served right away.
+// The questions are picked with the seed of the store, so the same database and seed gives the same questions.
+// Stores with a group also weigh the questions by the history in the database.
+type SQLiteStore struct {
+	db     *sql.DB
+	packs  []string
+	filter Filter
+	rand   *rand.Rand
+	group  string
+}
+
+// candidate is a question which can be served, translated is true if the question is in the language of the store
+type candidate struct {
+	id         string
+	translated bool
+}
+
+func (s *SQLiteStore) GetFourUnique(usedIds []string) ([]models.Question, error) {
+	candidates, err := s.candidates(usedIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) < NumberOfQuestionsToFind {
+		return nil, fmt.Errorf("unable to find 4 questions, found %d", len(candidates))
+	}
+	// the candidates are ordered by id, so the shuffle only depends on the seed,
+	// and on the history when the store has a group
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	if s.group == "" {
+		s.rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	} else {
+		weigh, err := weights(SQLiteHistory{DB: s.db}, s.group, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		weightedShuffle(order, func(i int) float64 { return weigh(candidates[i].id) }, s.rand)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return candidates[order[i]].translated && !candidates[order[j]].translated
+	})
+
+	var ids []string
+	for _, i := range order[:NumberOfQuestionsToFind] {
+		ids = append(ids, candidates[i].id)
+	}
+	result, err := s.questions(ids)
+	if err != nil {
+		return nil, err
+	}
+	if s.filter.Language != "" {
+		result = forLanguage(result, s.filter.Language)
+	}
+	return result, nil
+}
+
+func (s *SQLiteStore) Remaining(usedIds []string) (int, error) {
+	candidates, err := s.candidates(usedIds)
+	return len(candidates), err
+}
+
+// UseGroup serves the questions the group has not played recently first
+func (s *SQLiteStore) UseGroup(group string) error {
+	s.group = group
+	return nil
+}
+
+func (s *SQLiteStore) Played(ids []string) error {
+	return SQLiteHistory{DB: s.db}.Record(s.group, ids, time.Now())
+}
+
+// candidates returns the ids of the questions which are allowed by the filter and not used, ordered by id
+func (s *SQLiteStore) candidates(usedIds []string) ([]candidate, error) {
+	query, args := s.selectQuery(usedIds)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to select questions: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.translated); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// questions returns the questions with the ids, in the order of the ids
+func (s *SQLiteStore) questions(ids []string) ([]models.Question, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM questions WHERE id IN (SELECT value FROM json_each(?))`, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("unable to select questions: %w", err)
+	}
+	defer rows.Close()
+
+	byId := make(map[string]models.Question)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var q models.Question
+		if err := json.Unmarshal([]byte(data), &q); err != nil {
+			return nil, fmt.Errorf("unable to read question '%s': %w", id, err)
+		}
+		byId[id] = q
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var result []models.Question
+	for _, id := range ids {
+		q, ok := byId[id]
+		if !ok {
+			return nil, fmt.Errorf("question '%s' was removed while it was selected", id)
+		}
+		result = append(result, q)
+	}
+	return result, nil
+}
+
+// selectQuery builds the query for the questions which are allowed by the filter and not used.
+// The lists are passed as JSON arrays, so the used ids are excluded by the database in one query.
+func (s *SQLiteStore) selectQuery(usedIds []string) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	translated := `0`
+	if s.filter.Language != "" {
+		translated = `EXISTS (SELECT 1 FROM question_translations t
+			WHERE t.question_id = q.id AND t.text != '' AND t.language IN (SELECT value FROM json_each(?)))`
+		args = append(args, jsonArray(FallbackChain(s.filter.Language)))
+	}
+
+	where = append(where, `q.id NOT IN (SELECT value FROM json_each(?))`)
+	args = append(args, jsonArray(usedIds))
+	if len(s.packs) > 0 {
+		where = append(where, `q.pack IN (SELECT value FROM json_each(?))`)
+		args = append(args, jsonArray(s.packs))
+	}
+	if s.filter.MaxRating != "" {
+		var allowed []string
+		for rating, level := range ratingLevels {
+			if level <= ratingLevels[s.filter.MaxRating] {
+				allowed = append(allowed, string(rating))
+			}
+		}
+		where = append(where, `q.rating IN (SELECT value FROM json_each(?))`)
+		args = append(args, jsonArray(allowed))
+	}
+	if len(s.filter.Categories) > 0 {
+		where = append(where, `EXISTS (SELECT 1 FROM question_categories c
+			WHERE c.question_id = q.id AND c.category IN (SELECT value FROM json_each(?)))`)
+		args = append(args, jsonArray(s.filter.Categories))
+	}
+	if s.filter.Language != "" {
+		// questions in neither the language nor the default language are not served
+		where = append(where, `EXISTS (SELECT 1 FROM question_translations t
+			WHERE t.question_id = q.id AND t.text != '' AND t.language IN (SELECT value FROM json_each(?)))`)
+		args = append(args, jsonArray(append(FallbackChain(s.filter.Language), DefaultLanguage)))
+	}
+
+	query := `SELECT q.id, ` + translated + ` FROM questions q WHERE ` + strings.Join(where, " AND ") + ` ORDER BY q.id`
+	return query, args
+}
+
+func jsonArray(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// ImportPack adds the pack and its questions to the database, existing packs and questions are updated
+func ImportPack(db *sql.DB, pack Pack) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m := pack.Manifest
+	languages := jsonArray(m.Languages)
+	if _, err := tx.Exec(`
+		INSERT INTO packs (id, name, description, languages, author, rating) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, description = excluded.description,
+			languages = excluded.languages, author = excluded.author, rating = excluded.rating`,
+		m.Id, m.Name, m.Description, languages, m.Author, m.Rating); err != nil {
+		return fmt.Errorf("unable to import pack '%s': %w", m.Id, err)
+	}
+	for _, q := range pack.Questions {
+		if err := insertQuestion(tx, m.Id, q); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AddQuestion adds the question to a pack in the database, the question is served to new rounds right away
+func AddQuestion(db *sql.DB, packId string, q models.Question) error {
+	if err := validate(q); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insertQuestion(tx, packId, q); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertQuestion(tx *sql.Tx, packId string, q models.Question) error {
+	// the text is resolved for each room, it is not stored
+	q.Text, q.Language = "", ""
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO questions (id, pack, type, rating, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET pack = excluded.pack, type = excluded.type,
+			rating = excluded.rating, data = excluded.data`,
+		q.Id, packId, q.Type, q.Rating, string(data)); err != nil {
+		return fmt.Errorf("unable to import question '%s': %w", q.Id, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM question_translations WHERE question_id = ?`, q.Id); err != nil {
+		return err
+	}
+	for language, text := range q.Question {
+		if _, err := tx.Exec(`INSERT INTO question_translations (question_id, language, text) VALUES (?, ?, ?)`,
+			q.Id, language, text); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM question_categories WHERE question_id = ?`, q.Id); err != nil {
+		return err
+	}
+	for _, category := range q.Categories {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO question_categories (question_id, category) VALUES (?, ?)`,
+			q.Id, category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package question
+
+import (
+	"database/sql"
+	"github.com/akselleirv/introspect/models"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "questions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	packs, err := LoadPacks(TestPacksDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range packs {
+		if err := ImportPack(db, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	db := newTestDB(t)
+	assert.NoError(t, Migrate(db), "migrations already applied are skipped")
+
+	var version int
+	assert.NoError(t, db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version))
+	assert.Equal(t, len(migrations), version)
+}
+
+func TestSQLiteSourcePacks(t *testing.T) {
+	db := newTestDB(t)
+	packs, err := LoadPacks(TestPacksDir)
+	assert.NoError(t, err)
+	assert.NoError(t, ImportPack(db, packs[0]), "importing a pack again updates it")
+
+	manifests, err := SQLiteSource{DB: db}.Packs()
+	assert.NoError(t, err)
+	assert.Len(t, manifests, 1)
+	assert.Equal(t, packs[0].Manifest, manifests[0])
+
+	_, err = SQLiteSource{DB: db}.NewStore([]string{"unknown"}, Filter{}, TestSeed)
+	assert.Error(t, err)
+}
+
+func TestSQLiteStoreGetFourUnique(t *testing.T) {
+	db := newTestDB(t)
+	store, err := SQLiteSource{DB: db}.NewStore(nil, Filter{Language: "no"}, TestSeed)
+	assert.NoError(t, err)
+
+	remaining, err := store.Remaining(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*NumberOfQuestionsToFind, remaining)
+
+	var used []string
+	for i := 0; i < 2; i++ {
+		questions, err := store.GetFourUnique(used)
+		assert.NoError(t, err)
+		assert.Len(t, questions, NumberOfQuestionsToFind)
+		for _, q := range questions {
+			assert.NotContains(t, used, q.Id)
+			assert.Equal(t, "no", q.Language)
+			assert.NotEmpty(t, q.Text)
+			used = append(used, q.Id)
+		}
+	}
+}
+
+func TestSQLiteStoreSeed(t *testing.T) {
+	db := newTestDB(t)
+	questions := func(seed int64) []string {
+		store, err := SQLiteSource{DB: db}.NewStore(nil, Filter{}, seed)
+		assert.NoError(t, err)
+		first, err := store.GetFourUnique(nil)
+		assert.NoError(t, err)
+		second, err := store.GetFourUnique(getQuestionIds(first))
+		assert.NoError(t, err)
+		return append(getQuestionIds(first), getQuestionIds(second)...)
+	}
+	assert.Equal(t, questions(TestSeed), questions(TestSeed))
+	assert.NotEqual(t, questions(TestSeed), questions(TestSeed+1))
+
+	before := questions(TestSeed)
+	assert.NoError(t, SQLiteHistory{DB: db}.Record("group", before[:4], time.Now()))
+	assert.Equal(t, before, questions(TestSeed), "the plays of other games do not change the order of the seed")
+}
+
+func TestSQLiteStoreFilter(t *testing.T) {
+	db := newTestDB(t)
+	for i, rating := range []models.ContentRating{models.SafeRating, models.PartyRating, models.AdultRating, models.AdultRating} {
+		q := models.Question{
+			Id:         "added-" + string(rune('a'+i)),
+			Question:   models.QuestionTranslations{"en": "added"},
+			Categories: []string{"Work"},
+			Rating:     rating,
+		}
+		assert.NoError(t, AddQuestion(db, "test", q))
+	}
+
+	store, err := SQLiteSource{DB: db}.NewStore([]string{"test"}, Filter{Categories: []string{"work"}}, TestSeed)
+	assert.NoError(t, err)
+	questions, err := store.GetFourUnique(nil)
+	assert.NoError(t, err)
+	assert.Len(t, questions, NumberOfQuestionsToFind, "categories are matched regardless of case")
+
+	store, err = SQLiteSource{DB: db}.NewStore(nil, Filter{Categories: []string{"work"}, MaxRating: models.PartyRating}, TestSeed)
+	assert.NoError(t, err)
+	_, err = store.GetFourUnique(nil)
+	assert.Error(t, err, "only two questions are in the category and rated at most party")
+
+	assert.Error(t, AddQuestion(db, "test", models.Question{Id: "no-translations"}))
+}
+
+func TestSQLiteHistory(t *testing.T) {
+	db := newTestDB(t)
+	h := SQLiteHistory{DB: db}
+	now := time.Unix(time.Now().Unix(), 0)
+	assert.NoError(t, h.Record("group", []string{"a", "b"}, now.Add(-HistoryWindow-time.Second)))
+	assert.NoError(t, h.Record("", []string{"a"}, now))
+	assert.NoError(t, h.Record("group", []string{"c"}, now))
+
+	plays, err := h.Plays()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 2, "b": 1, "c": 1}, plays)
+	seen, err := h.Seen("group")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{"c": now}, seen, "questions played before the window are forgotten")
+
+	assert.NoError(t, h.Record("stopped", []string{"a"}, now))
+	assert.NoError(t, h.Record("group", []string{"d"}, now.Add(HistoryWindow+time.Second)))
+	seen, err = h.Seen("stopped")
+	assert.NoError(t, err)
+	assert.Empty(t, seen, "the groups which stopped playing are forgotten as well")
+}
@@ -1,46 +1,90 @@
 package room
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"github.com/akselleirv/introspect/client"
 	"github.com/akselleirv/introspect/game"
+	"github.com/akselleirv/introspect/handler"
 	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
 	"github.com/gorilla/websocket"
 	"log"
 	"sync"
 )
 
-const QuestionsFilePath = "./questions.json"
+var (
+	ErrRoomFull        = errors.New("room is full")
+	ErrPlayerNameTaken = errors.New("player name is taken")
+)
+
+// spectatorEvents are the only events a spectator is allowed to send, they can not take part in the game
+var spectatorEvents = map[string]bool{
+	"ping":                  true,
+	"get_game_snapshot":     true,
+	"get_game_stats":        true,
+	"list_custom_questions": true,
+}
 
 type Roomer interface {
-	AddClient(c *websocket.Conn, name string)
+	AddClient(c *websocket.Conn, name string) error
+	// AddSpectator adds a client which receives all broadcasts but is not a player in the game
+	AddSpectator(c *websocket.Conn, name string) error
+	Spectators() []string
+	// Snapshot returns the state of the game and the spectators, it is sent to clients joining a game in progress
+	Snapshot() []byte
 	Broadcast(msg []byte)
+	// BroadcastLobbyUpdate sends the status of the lobby, the action tells what triggered the update
+	BroadcastLobbyUpdate(player string, action models.LobbyUpdateAction)
 	SendMsg(clientName string, msg []byte)
 	Game() game.Gamer
 	IsPlayerNameAvailable(name string) bool
 	IsRoomJoinable() bool
+	IsRoomFull() bool
+	// IsEmpty returns true when there are no players or spectators in the room
+	IsEmpty() bool
+	Code() string
+	IsInviteOnly() bool
+	IsPasswordProtected() bool
+	IsPasswordValid(password string) bool
+	// OnPlayerLeft registers a function which is called after a player has left the room
+	OnPlayerLeft(fn func(playerName string, d game.Departure))
 }
 
 type Room struct {
-	name       string
-	clients    map[string]client.Clienter
-	msgHandler func(msg map[string]interface{})
-	deleteRoom func()
-	mu         sync.RWMutex
+	name         string
+	code         string
+	access       models.RoomAccess
+	clients      map[string]client.Clienter
+	spectators   map[string]client.Clienter
+	msgHandler   func(msg map[string]interface{})
+	onPlayerLeft func(playerName string, d game.Departure)
+	deleteRoom   func()
+	mu           sync.RWMutex
 
 	game game.Game
 }
 
-func NewRoom(name string, initEventHandlers func(r Roomer), handleMsg func(msg map[string]interface{}), deleteRoom func()) *Room {
-	log.Printf("creating new Room: %s", name)
+// NewRoom creates a room identified by code, the name is only used when the room was given a name by the creator
+func NewRoom(code string, opts models.CreateRoom, questions question.Source, initEventHandlers func(r Roomer), handleMsg func(msg map[string]interface{}), deleteRoom func()) *Room {
+	name := opts.Name
+	if name == "" {
+		name = code
+	}
+	log.Printf("creating new Room: %s (%s)", name, code)
 	r := &Room{
 		name:       name,
+		code:       code,
+		access:     opts.RoomAccess,
 		clients:    make(map[string]client.Clienter),
-		game:       game.NewGame(QuestionsFilePath),
+		spectators: make(map[string]client.Clienter),
+		game:       game.NewGame(questions, opts.Settings),
 		msgHandler: handleMsg,
 		deleteRoom: deleteRoom,
 		mu:         sync.RWMutex{},
 	}
+	r.game.SetName(name)
 	initEventHandlers(r)
 	return r
 }
@@ -48,60 +92,167 @@ func NewRoom(name string, initEventHandlers func(r Roomer), handleMsg func(msg m
 func (r *Room) removeClient(name string) {
 	r.mu.Lock()
 	delete(r.clients, name)
-	r.game.RemovePlayer(name)
+	d := r.game.RemovePlayer(name)
 	log.Printf("removed client '%s' from Room '%s'", name, r.name)
-	if len(r.clients) == 0 {
-		log.Printf("deleting Room '%s' -  no more players", r.name)
-		r.deleteRoom()
+	noPlayers := len(r.clients) == 0
+	isEmpty := r.isEmpty()
+	r.mu.Unlock()
+	if isEmpty {
+		r.delete()
 	}
+	r.BroadcastLobbyUpdate(name, models.Left)
+	if r.onPlayerLeft != nil && !noPlayers {
+		r.onPlayerLeft(name, d)
+	}
+}
+
+func (r *Room) removeSpectator(name string) {
+	r.mu.Lock()
+	delete(r.spectators, name)
+	log.Printf("removed spectator '%s' from Room '%s'", name, r.name)
+	isEmpty := r.isEmpty()
 	r.mu.Unlock()
-	playersUpdate, isAllReady := r.Game().GetRoomStatus()
-	b, _ := json.Marshal(models.LobbyRoomUpdate{
-		Event:      "lobby_room_update",
-		Players:    playersUpdate,
-		IsAllReady: isAllReady,
-		ActionTrigger: models.LobbyActionTrigger{
-			Player: name,
-			Action: models.Left,
-		},
-	})
-	r.Broadcast(b)
+	if isEmpty {
+		r.delete()
+	}
+	r.BroadcastLobbyUpdate(name, models.Left)
 }
 
-func (r *Room) AddClient(c *websocket.Conn, name string) {
-	if _, ok := r.clients[name]; !ok {
-		log.Printf("adding player '%s' to Room: '%s'", name, r.name)
+// isEmpty returns true when there are no players or spectators left, r.mu must be held
+func (r *Room) isEmpty() bool {
+	return len(r.clients) == 0 && len(r.spectators) == 0
+}
 
-		r.mu.Lock()
-		r.clients[name] = client.NewClient(name, c, r.msgHandler, func() { r.removeClient(name) })
+// delete removes the room from the server. r.mu must not be held, since the server takes its own lock
+// and checks if the room is empty while holding it.
+func (r *Room) delete() {
+	log.Printf("deleting Room '%s' -  no more players", r.name)
+	r.deleteRoom()
+}
+
+func (r *Room) AddClient(c *websocket.Conn, name string) error {
+	r.mu.Lock()
+	if !r.isNameAvailable(name) {
 		r.mu.Unlock()
+		return ErrPlayerNameTaken
+	}
+	if len(r.clients) >= r.game.Settings().MaxPlayers || !r.game.AddPlayer(name) {
+		r.mu.Unlock()
+		return ErrRoomFull
+	}
+	log.Printf("adding player '%s' to Room: '%s'", name, r.name)
+	r.clients[name] = client.NewClient(name, c, r.playerMsgHandler(name), func() { r.removeClient(name) })
+	r.mu.Unlock()
 
-		r.game.AddPlayer(name)
+	r.BroadcastLobbyUpdate(name, models.Joined)
+	if r.game.IsQueued(name) {
+		b, _ := json.Marshal(models.GenericEvent{Event: "player_queued_for_next_round", Player: name})
+		r.SendMsg(name, b)
+		r.SendMsg(name, r.Snapshot())
+	}
+	return nil
+}
 
-		playersUpdate, isAllReady := r.Game().GetRoomStatus()
-		b, _ := json.Marshal(models.LobbyRoomUpdate{
-			Event:      "lobby_room_update",
-			Players:    playersUpdate,
-			IsAllReady: isAllReady,
-			ActionTrigger: models.LobbyActionTrigger{
-				Player: name,
-				Action: models.Joined,
-			},
-		})
-		r.Broadcast(b)
-	} else {
-		log.Printf("awkward this error should never been diplayed - silently failing adding client to room - player '%s' already exist", name)
+func (r *Room) AddSpectator(c *websocket.Conn, name string) error {
+	r.mu.Lock()
+	if !r.isNameAvailable(name) {
+		r.mu.Unlock()
+		return ErrPlayerNameTaken
 	}
+	log.Printf("adding spectator '%s' to Room: '%s'", name, r.name)
+	r.spectators[name] = client.NewClient(name, c, r.spectatorMsgHandler(name), func() { r.removeSpectator(name) })
+	r.mu.Unlock()
+
+	r.BroadcastLobbyUpdate(name, models.Joined)
+	r.SendMsg(name, r.Snapshot())
+	return nil
 }
 
+// playerMsgHandler passes on the events of a player, the host and author checks rely on the player being the sender
+func (r *Room) playerMsgHandler(name string) func(msg map[string]interface{}) {
+	return func(msg map[string]interface{}) {
+		// a player can only send events on behalf of itself
+		msg["player"] = name
+		r.msgHandler(msg)
+	}
+}
+
+// spectatorMsgHandler only passes on the events a spectator is allowed to send
+func (r *Room) spectatorMsgHandler(name string) func(msg map[string]interface{}) {
+	return func(msg map[string]interface{}) {
+		if e, _ := msg[handler.Event].(string); !spectatorEvents[e] {
+			log.Printf("spectator '%s' is not allowed to send event '%s'", name, e)
+			return
+		}
+		// a spectator can only send events on behalf of itself
+		msg["player"] = name
+		r.msgHandler(msg)
+	}
+}
+
+func (r *Room) Snapshot() []byte {
+	snapshot := r.Game().Snapshot()
+	snapshot.Spectators = r.Spectators()
+	b, _ := json.Marshal(snapshot)
+	return b
+}
+
+func (r *Room) Spectators() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []string
+	for name := range r.spectators {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (r *Room) BroadcastLobbyUpdate(player string, action models.LobbyUpdateAction) {
+	playersUpdate, isAllReady := r.Game().GetRoomStatus()
+	settings := r.Game().Settings()
+	b, _ := json.Marshal(models.LobbyRoomUpdate{
+		Event:            "lobby_room_update",
+		Players:          playersUpdate,
+		Spectators:       r.Spectators(),
+		IsAllReady:       isAllReady,
+		MinPlayers:       settings.MinPlayers,
+		MaxPlayers:       settings.MaxPlayers,
+		Teams:            r.Game().Teams(),
+		Packs:            settings.Packs,
+		MaxRounds:        r.Game().MaxRounds(),
+		RecycleQuestions: settings.RecycleQuestions,
+		ActionTrigger: models.LobbyActionTrigger{
+			Player: player,
+			Action: action,
+		},
+	})
+	r.Broadcast(b)
+}
+
+// Broadcast sends the message to the players and spectators. The recipients are copied under r.mu,
+// so the message is not sent while holding the lock.
 func (r *Room) Broadcast(msg []byte) {
+	r.mu.RLock()
+	recipients := make([]client.Clienter, 0, len(r.clients)+len(r.spectators))
 	for _, p := range r.clients {
-		p.Send(msg)
+		recipients = append(recipients, p)
+	}
+	for _, s := range r.spectators {
+		recipients = append(recipients, s)
+	}
+	r.mu.RUnlock()
+	for _, c := range recipients {
+		c.Send(msg)
 	}
 }
 
 func (r *Room) SendMsg(clientName string, msg []byte) {
+	r.mu.RLock()
 	p, ok := r.clients[clientName]
+	if !ok {
+		p, ok = r.spectators[clientName]
+	}
+	r.mu.RUnlock()
 	if !ok {
 		log.Println("unable to find clientName: ", clientName)
 		return
@@ -112,15 +263,48 @@ func (r *Room) SendMsg(clientName string, msg []byte) {
 func (r *Room) Game() game.Gamer { return &r.game }
 
 func (r *Room) IsPlayerNameAvailable(name string) bool {
-	if _, exist := r.clients[name]; exist {
-		return false
-	}
-	return true
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isNameAvailable(name)
 }
 
-func (r *Room) IsRoomJoinable() bool {
+// isNameAvailable checks both players and spectators since they share the names, r.mu must be held
+func (r *Room) isNameAvailable(name string) bool {
+	_, isPlayer := r.clients[name]
+	_, isSpectator := r.spectators[name]
+	return !isPlayer && !isSpectator
+}
+
+func (r *Room) IsRoomFull() bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	rdy, _ := r.Game().IsPlayersReady()
-	return rdy == false
+	return len(r.clients) >= r.game.Settings().MaxPlayers
+}
+
+func (r *Room) IsEmpty() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isEmpty()
+}
+
+// IsRoomJoinable returns true if there is room for another player,
+// players joining a game in progress are queued for the next round
+func (r *Room) IsRoomJoinable() bool {
+	return !r.IsRoomFull()
+}
+
+func (r *Room) OnPlayerLeft(fn func(playerName string, d game.Departure)) { r.onPlayerLeft = fn }
+
+func (r *Room) Code() string { return r.code }
+
+func (r *Room) IsInviteOnly() bool { return r.access.InviteOnly }
+
+func (r *Room) IsPasswordProtected() bool { return r.access.Password != "" }
+
+// IsPasswordValid returns true if the password matches the room password or if the room has no password
+func (r *Room) IsPasswordValid(password string) bool {
+	if !r.IsPasswordProtected() {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.access.Password), []byte(password)) == 1
 }
@@ -0,0 +1,61 @@
+package room
+
+import (
+	"fmt"
+	"github.com/akselleirv/introspect/client"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+// fakeClient counts the messages it is sent
+type fakeClient struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (c *fakeClient) Send([]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent++
+}
+
+func TestMsgHandlersPinThePlayer(t *testing.T) {
+	var received map[string]interface{}
+	r := &Room{msgHandler: func(msg map[string]interface{}) { received = msg }}
+
+	r.playerMsgHandler("guest")(map[string]interface{}{"event": "lobby_select_packs", "player": "host"})
+	assert.Equal(t, "guest", received["player"], "a player can not send events on behalf of the host")
+
+	received = nil
+	r.spectatorMsgHandler("watcher")(map[string]interface{}{"event": "lobby_select_packs", "player": "host"})
+	assert.Nil(t, received, "spectators can not select packs")
+	r.spectatorMsgHandler("watcher")(map[string]interface{}{"event": "get_game_snapshot", "player": "host"})
+	assert.Equal(t, "watcher", received["player"])
+}
+
+func TestBroadcastWhileSpectatorsComeAndGo(t *testing.T) {
+	player := &fakeClient{}
+	r := &Room{clients: map[string]client.Clienter{"p1": player}, spectators: map[string]client.Clienter{}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		name := fmt.Sprintf("spectator %d", i)
+		go func() {
+			defer wg.Done()
+			r.mu.Lock()
+			r.spectators[name] = &fakeClient{}
+			r.mu.Unlock()
+			r.mu.Lock()
+			delete(r.spectators, name)
+			r.mu.Unlock()
+		}()
+		go func() {
+			defer wg.Done()
+			r.Broadcast([]byte("msg"))
+			r.SendMsg("p1", []byte("msg"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 20, player.sent)
+}
@@ -1,71 +1,212 @@
 package server
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/akselleirv/introspect/events"
+	"github.com/akselleirv/introspect/game"
 	"github.com/akselleirv/introspect/handler"
 	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
 	"github.com/akselleirv/introspect/room"
 	"github.com/gorilla/websocket"
 	"log"
+	"math/big"
 	"os"
 	"sync"
+	"time"
 )
 
+const (
+	// the alphabet leaves out characters that are easily mixed up, like 0 and O
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	roomCodeLength   = 6
+	// EmptyRoomTimeout is how long a created room is kept when nobody joins it
+	EmptyRoomTimeout = 10 * time.Minute
+)
+
+var ErrRoomNameTaken = errors.New("room name is already taken")
+
 type Server interface {
-	NewConn(c *websocket.Conn, playerName, roomName string)
-	IsGameInfoValid(roomName, playerName string) bool
+	NewConn(c *websocket.Conn, join models.JoinRoom)
+	IsGameInfoValid(join models.JoinRoom) models.GameInfo
+	CreateRoom(req models.CreateRoom) (models.RoomCreated, error)
 }
 
 type Serve struct {
+	// rooms are stored by their code, and by their name if they were given one
 	rooms map[string]room.Roomer
-	mu    sync.RWMutex
+	// questions are used by all the rooms
+	questions question.Source
+	// emptyRoomTimeout is how long a room created through CreateRoom waits for someone to join
+	emptyRoomTimeout time.Duration
+	mu               sync.RWMutex
 }
 
-func NewServer() *Serve {
-	return &Serve{rooms: make(map[string]room.Roomer), mu: sync.RWMutex{}}
+func NewServer(questions question.Source) *Serve {
+	return &Serve{rooms: make(map[string]room.Roomer), questions: questions, emptyRoomTimeout: EmptyRoomTimeout, mu: sync.RWMutex{}}
 }
 
-func (s *Serve) NewConn(c *websocket.Conn, playerName, roomName string) {
-	if exist := s.roomExist(roomName); !exist {
-		h := handler.NewHandler(log.New(os.Stdout, "", 64))
-		initEventHandlers := events.Setup(h)
-		msgHandler := h.HandleMsg()
-		r, err := s.createRoom(roomName, initEventHandlers, msgHandler)
+// NewConn adds the player to the room. The room is only created if it does not exist and join.Create is true,
+// otherwise joining a room which does not exist is refused.
+func (s *Serve) NewConn(c *websocket.Conn, join models.JoinRoom) {
+	if exist := s.roomExist(join.Room); !exist {
+		if !join.Create {
+			log.Printf("refusing player '%s' to join room '%s': %s", join.Player, join.Room, models.RoomNotFound)
+			s.refuseConn(c, join.Player, models.RoomNotFound)
+			return
+		}
+		// rooms which are not created through CreateRoom are public and use the name as code
+		r, err := s.createRoom(join.Room, models.CreateRoom{RoomAccess: models.RoomAccess{Password: join.Password}})
 		if err != nil {
 			log.Println(err)
 			return
 		}
-		s.registerNewRoom(roomName, r)
+		// another player may have created the room in the meantime, the player then joins that room instead
+		if err := s.registerNewRoom(r, ""); err != nil {
+			log.Println(err)
+		}
 	}
 
-	s.addPlayerToRoom(c, playerName, roomName)
+	if r, ok := s.getRoom(join.Room); ok {
+		if reason := refuseAccess(join.Room, r, join.Password); reason != "" {
+			log.Printf("refusing player '%s' to join room '%s': %s", join.Player, join.Room, reason)
+			s.refuseConn(c, join.Player, reason)
+			return
+		}
+	}
+
+	s.addPlayerToRoom(c, join)
 }
 
-func (s *Serve) IsGameInfoValid(roomName, playerName string) (playerNameAvailable bool, roomIsJoinable bool) {
-	if r, ok := s.getRoom(roomName); ok {
-		playerNameAvailable = r.IsPlayerNameAvailable(playerName)
-		roomIsJoinable = r.IsRoomJoinable()
-		return playerNameAvailable, roomIsJoinable
+// CreateRoom creates a room with a generated code which is returned to the creator
+func (s *Serve) CreateRoom(req models.CreateRoom) (models.RoomCreated, error) {
+	if req.Name != "" && s.roomExist(req.Name) {
+		return models.RoomCreated{}, fmt.Errorf("%w: '%s'", ErrRoomNameTaken, req.Name)
+	}
+	settings, err := game.ValidateSettings(req.Settings)
+	if err != nil {
+		return models.RoomCreated{}, err
+	}
+	if err := game.ValidatePacks(s.questions, settings); err != nil {
+		return models.RoomCreated{}, err
+	}
+	req.Settings = settings
+	code, err := s.newRoomCode()
+	if err != nil {
+		return models.RoomCreated{}, err
+	}
+	r, err := s.createRoom(code, req)
+	if err != nil {
+		return models.RoomCreated{}, err
+	}
+	if err := s.registerNewRoom(r, req.Name); err != nil {
+		return models.RoomCreated{}, err
+	}
+	time.AfterFunc(s.emptyRoomTimeout, func() { s.deleteRoomIfEmpty(r, req.Name) })
+	return models.RoomCreated{Code: code, Name: req.Name, Settings: settings}, nil
+}
+
+func (s *Serve) IsGameInfoValid(join models.JoinRoom) models.GameInfo {
+	r, ok := s.getRoom(join.Room)
+	if !ok {
+		// the player name is available, but the room must be created before it can be joined
+		return models.GameInfo{PlayerNameAvailable: true, Reason: models.RoomNotFound}
+	}
+
+	info := models.GameInfo{
+		RoomExists:          true,
+		PlayerNameAvailable: r.IsPlayerNameAvailable(join.Player),
+		RoomIsJoinable:      r.IsRoomJoinable(),
+		RoomFull:            r.IsRoomFull(),
+		Reason:              refuseAccess(join.Room, r, join.Password),
+	}
+	if join.Role == models.SpectatorRole {
+		// spectators do not take a seat and can watch a game in progress
+		info.RoomFull = false
+		info.RoomIsJoinable = true
 	} else {
-		// room does not exist - both are then valid
-		playerNameAvailable = true
-		roomIsJoinable = true
-		return playerNameAvailable, roomIsJoinable
+		info.JoinsNextRound = r.Game().IsStarted()
 	}
+	if info.Reason != "" {
+		info.RoomIsJoinable = false
+	} else if !info.PlayerNameAvailable {
+		info.Reason = models.PlayerNameTaken
+	} else if info.RoomFull {
+		info.RoomIsJoinable = false
+		info.Reason = models.RoomFull
+	} else if !info.RoomIsJoinable {
+		info.Reason = models.RoomNotJoinable
+	}
+	return info
 }
 
-func (s *Serve) deleteRoom(name string) {
+// refuseAccess returns the reason for refusing access to the room, or an empty string if access is granted.
+// The key is what the player used to find the room, which is either the code or the name of the room.
+func refuseAccess(key string, r room.Roomer, password string) models.JoinRefusal {
+	if r.IsInviteOnly() && key != r.Code() {
+		return models.InviteOnly
+	}
+	if r.IsPasswordProtected() && password == "" {
+		return models.PasswordRequired
+	}
+	if !r.IsPasswordValid(password) {
+		return models.WrongPassword
+	}
+	return ""
+}
+
+func (s *Serve) refuseConn(c *websocket.Conn, playerName string, reason models.JoinRefusal) {
+	err := c.WriteJSON(models.ErrorMsg{Event: "unable_to_join_room", Error: string(reason)})
+	if err != nil {
+		log.Printf("error sending message to player '%s': %s", playerName, err.Error())
+	}
+	c.Close()
+}
+
+func (s *Serve) deleteRoom(code, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rooms, code)
+	if name != "" {
+		delete(s.rooms, name)
+	}
+}
+
+// deleteRoomIfEmpty deletes a room nobody has joined, the room is left alone if it has already been replaced.
+// The room is checked before s.mu is taken, since the room holds its own lock when it deletes itself.
+func (s *Serve) deleteRoomIfEmpty(r room.Roomer, name string) {
+	if !r.IsEmpty() {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.rooms[name]; ok {
+	if s.rooms[r.Code()] != r {
+		return
+	}
+	log.Printf("deleting Room '%s' - nobody joined", r.Code())
+	delete(s.rooms, r.Code())
+	if name != "" && s.rooms[name] == r {
 		delete(s.rooms, name)
 	}
 }
 
-func (s *Serve) addPlayerToRoom(c *websocket.Conn, playerName, roomName string) {
-	if r, ok := s.getRoom(roomName); ok {
-		r.AddClient(c, playerName)
+func (s *Serve) addPlayerToRoom(c *websocket.Conn, join models.JoinRoom) {
+	playerName := join.Player
+	if r, ok := s.getRoom(join.Room); ok {
+		var err error
+		if join.Role == models.SpectatorRole {
+			err = r.AddSpectator(c, playerName)
+		} else {
+			err = r.AddClient(c, playerName)
+		}
+		if errors.Is(err, room.ErrPlayerNameTaken) {
+			s.refuseConn(c, playerName, models.PlayerNameTaken)
+		} else if err != nil {
+			log.Printf("refusing player '%s' to join room '%s': %s", playerName, join.Room, err)
+			s.refuseConn(c, playerName, models.RoomFull)
+		}
 	} else {
 		log.Println("unable to find room - this error message should not printed - this is awkward")
 		err := c.WriteJSON(models.GenericEvent{Event: "unable_to_find_room", Player: playerName})
@@ -83,17 +224,50 @@ func (s *Serve) roomExist(roomName string) bool {
 	return ok
 }
 
-func (s *Serve) createRoom(name string, initEventHandlers func(r room.Roomer), msgHandler func(msg map[string]interface{})) (room.Roomer, error) {
-	if exist := s.roomExist(name); exist {
-		return nil, fmt.Errorf("room '%s' already exists", name)
+func (s *Serve) createRoom(code string, opts models.CreateRoom) (room.Roomer, error) {
+	if exist := s.roomExist(code); exist {
+		return nil, fmt.Errorf("room '%s' already exists", code)
 	}
-	return room.NewRoom(name, initEventHandlers, msgHandler, func() { s.deleteRoom(name) }), nil
+	h := handler.NewHandler(log.New(os.Stdout, "", 64))
+	initEventHandlers := events.Setup(h)
+	msgHandler := h.HandleMsg()
+	return room.NewRoom(code, opts, s.questions, initEventHandlers, msgHandler, func() { s.deleteRoom(code, opts.Name) }), nil
 }
 
-func (s *Serve) registerNewRoom(name string, r room.Roomer) {
+// registerNewRoom stores the room by its code, and by its name if given.
+// The check and the insert happen under the same lock, so two rooms can not end up with the same code or name.
+func (s *Serve) registerNewRoom(r room.Roomer, name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.rooms[name] = r
+	if _, exist := s.rooms[r.Code()]; exist {
+		return fmt.Errorf("room '%s' already exists", r.Code())
+	}
+	if _, exist := s.rooms[name]; name != "" && exist {
+		return fmt.Errorf("%w: '%s'", ErrRoomNameTaken, name)
+	}
+	s.rooms[r.Code()] = r
+	if name != "" {
+		s.rooms[name] = r
+	}
+	return nil
+}
+
+// newRoomCode generates a short random code which is not used by any other room
+func (s *Serve) newRoomCode() (string, error) {
+	for i := 0; i < 100; i++ {
+		code := make([]byte, roomCodeLength)
+		for j := range code {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(roomCodeAlphabet))))
+			if err != nil {
+				return "", fmt.Errorf("unable to generate room code: %w", err)
+			}
+			code[j] = roomCodeAlphabet[n.Int64()]
+		}
+		if !s.roomExist(string(code)) {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("unable to find an unused room code")
 }
 
 func (s *Serve) getRoom(roomName string) (room.Roomer, bool) {
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"github.com/akselleirv/introspect/game"
+	"github.com/akselleirv/introspect/models"
+	"github.com/akselleirv/introspect/question"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var TestQuestions = question.PackDir{Dir: "../testPacks"}
+
+func TestNewRoomCode(t *testing.T) {
+	s := NewServer(TestQuestions)
+	codes := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := s.newRoomCode()
+		assert.NoError(t, err)
+		assert.Len(t, code, roomCodeLength)
+		for _, c := range code {
+			assert.Contains(t, roomCodeAlphabet, string(c))
+		}
+		codes[code] = true
+	}
+	assert.Len(t, codes, 100, "the codes are random")
+
+	created, err := s.CreateRoom(models.CreateRoom{})
+	assert.NoError(t, err)
+	r, _ := s.getRoom(created.Code)
+	assert.Error(t, s.registerNewRoom(r, ""), "two rooms can not have the same code")
+}
+
+func TestCreateRoom(t *testing.T) {
+	s := NewServer(TestQuestions)
+	created, err := s.CreateRoom(models.CreateRoom{Name: "party"})
+	assert.NoError(t, err)
+	assert.Len(t, created.Code, roomCodeLength)
+	assert.Equal(t, "party", created.Name)
+	assert.Equal(t, game.DefaultMaxPlayers, created.Settings.MaxPlayers, "the defaults are filled in")
+	assert.True(t, s.roomExist(created.Code))
+	assert.True(t, s.roomExist("party"))
+
+	_, err = s.CreateRoom(models.CreateRoom{Name: "party"})
+	assert.ErrorIs(t, err, ErrRoomNameTaken)
+
+	_, err = s.CreateRoom(models.CreateRoom{Settings: models.RoomSettings{MinPlayers: 5, MaxPlayers: 4}})
+	assert.ErrorIs(t, err, game.ErrInvalidSettings)
+}
+
+func TestCreateRoomConcurrently(t *testing.T) {
+	s := NewServer(TestQuestions)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.CreateRoom(models.CreateRoom{Name: "party"}); err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, created, "only one room can get the name")
+	assert.Len(t, s.rooms, 2, "the room is stored by its code and its name")
+}
+
+func TestDeleteEmptyRoom(t *testing.T) {
+	s := NewServer(TestQuestions)
+	s.emptyRoomTimeout = 10 * time.Millisecond
+	created, err := s.CreateRoom(models.CreateRoom{Name: "party"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return !s.roomExist(created.Code) && !s.roomExist("party") },
+		time.Second, 5*time.Millisecond, "nobody joined the room")
+
+	s = NewServer(TestQuestions)
+	created, err = s.CreateRoom(models.CreateRoom{Name: "party"})
+	assert.NoError(t, err)
+	r, _ := s.getRoom(created.Code)
+	s.deleteRoom(created.Code, "party")
+	replaced, err := s.CreateRoom(models.CreateRoom{Name: "party"})
+	assert.NoError(t, err)
+	s.deleteRoomIfEmpty(r, "party")
+	assert.True(t, s.roomExist("party"), "the room which took over the name is left alone")
+	assert.True(t, s.roomExist(replaced.Code))
+}
+
+func TestDeleteEmptyRoomWhileTheLastPlayerLeaves(t *testing.T) {
+	s := NewServer(TestQuestions)
+	upgrader := websocket.Upgrader{}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		s.NewConn(c, models.JoinRoom{Player: "p1", Room: req.URL.Query().Get("room"), Role: models.PlayerRole})
+	}))
+	defer ws.Close()
+
+	for i := 0; i < 20; i++ {
+		created, err := s.CreateRoom(models.CreateRoom{})
+		assert.NoError(t, err)
+		r, _ := s.getRoom(created.Code)
+		c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ws.URL, "http")+"?room="+created.Code, nil)
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool { return !r.IsEmpty() }, time.Second, time.Millisecond)
+
+		// the timer and the last player leaving delete the room at the same time
+		deleted := make(chan struct{})
+		go func() {
+			for s.roomExist(created.Code) {
+				s.deleteRoomIfEmpty(r, "")
+			}
+			close(deleted)
+		}()
+		c.Close()
+		select {
+		case <-deleted:
+		case <-time.After(time.Second):
+			t.Fatal("deleting the room is deadlocked")
+		}
+	}
+}
+
+func TestIsGameInfoValid(t *testing.T) {
+	s := NewServer(TestQuestions)
+	protected, err := s.CreateRoom(models.CreateRoom{Name: "party", RoomAccess: models.RoomAccess{Password: "secret"}})
+	assert.NoError(t, err)
+	invite, err := s.CreateRoom(models.CreateRoom{Name: "secret-party", RoomAccess: models.RoomAccess{InviteOnly: true}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		join   models.JoinRoom
+		reason models.JoinRefusal
+	}{
+		{"unknown room", models.JoinRoom{Player: "p1", Room: "nope"}, models.RoomNotFound},
+		{"missing password", models.JoinRoom{Player: "p1", Room: "party"}, models.PasswordRequired},
+		{"wrong password", models.JoinRoom{Player: "p1", Room: "party", Password: "guess"}, models.WrongPassword},
+		{"right password", models.JoinRoom{Player: "p1", Room: "party", Password: "secret"}, ""},
+		{"code and password", models.JoinRoom{Player: "p1", Room: protected.Code, Password: "secret"}, ""},
+		{"invite only by name", models.JoinRoom{Player: "p1", Room: "secret-party"}, models.InviteOnly},
+		{"invite only by code", models.JoinRoom{Player: "p1", Room: invite.Code}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := s.IsGameInfoValid(tt.join)
+			assert.Equal(t, tt.reason, info.Reason)
+			assert.Equal(t, tt.reason == "", info.RoomIsJoinable)
+		})
+	}
+}